@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/git-saj/go-media-control/handlers"
 	"github.com/git-saj/go-media-control/internal/auth"
@@ -54,6 +59,8 @@ func main() {
 			os.Exit(1)
 		}
 		authHandlers = auth.NewAuthHandlers(authService, logger)
+		h.UseTokens(authService.Tokens())
+		h.UseSessions(authService.Sessions())
 		logger.Info("Authentication enabled")
 	} else {
 		logger.Info("Authentication disabled")
@@ -64,133 +71,47 @@ func main() {
 	r.Use(middleware.Logger)    // Log requests
 	r.Use(middleware.Recoverer) // Recover from panics
 
-	// Handle routing based on base path
+	// Register the routes at the root, or under the base path
 	if cfg.BasePath == "/" {
-		// Root path - mount routes directly
-		// Public routes (no authentication required)
-		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"status":"ok","service":"go-media-control"}`))
-		})
-
-		if !cfg.DisableAuth {
-			// Authentication routes (no auth required)
-			r.Route("/auth", func(r chi.Router) {
-				r.Get("/login", authHandlers.LoginHandler)
-				r.Get("/callback", authHandlers.CallbackHandler)
-				r.Get("/logout", authHandlers.LogoutHandler)
-				r.Get("/logged-out", authHandlers.LoggedOutHandler)
-				r.Post("/back-channel-logout", authHandlers.BackChannelLogoutHandler)
-				r.Get("/user", authHandlers.UserInfoHandler) // For debugging
-			})
-
-			// Protected routes (authentication required)
-			r.Group(func(r chi.Router) {
-				r.Use(authService.RequireAuth) // Apply authentication middleware
-
-				// Serve static files with base path awareness
-				staticPrefix := cfg.BasePath + "static/"
-				r.Handle("/static/*", http.StripPrefix(staticPrefix, staticServe))
-
-				// Define protected routes
-				r.Get("/", h.HomeHandler)
-				r.Get("/api/media", h.MediaHandler)
-				r.Get("/api/epg", h.EpgHandler)
-				r.Post("/api/send", h.SendHandler)
-				r.Get("/search", h.SearchHandler)
-				r.Post("/search", h.SearchHandler)
-				r.Get("/refresh", h.RefreshHandler)
-			})
-		} else {
-			// No authentication - all routes are public
-			// Serve static files with base path awareness
-			staticPrefix := cfg.BasePath + "static/"
-			r.Handle("/static/*", http.StripPrefix(staticPrefix, staticServe))
-
-			// Define public routes
-			r.Get("/", h.HomeHandler)
-			r.Get("/api/media", h.MediaHandler)
-			r.Get("/api/epg", h.EpgHandler)
-			r.Post("/api/send", h.SendHandler)
-			r.Get("/search", h.SearchHandler)
-			r.Post("/search", h.SearchHandler)
-			r.Get("/refresh", h.RefreshHandler)
-		}
+		routes(r, cfg, h, authService, authHandlers, staticServe)
 	} else {
-		// Subpath - mount under base path
 		basePath := cfg.BasePath[:len(cfg.BasePath)-1] // Remove trailing slash
 		r.Route(basePath, func(r chi.Router) {
-			// Public routes (no authentication required)
-			r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte(`{"status":"ok","service":"go-media-control"}`))
-			})
-
-			if !cfg.DisableAuth {
-				// Authentication routes (no auth required)
-				r.Route("/auth", func(r chi.Router) {
-					r.Get("/login", authHandlers.LoginHandler)
-					r.Get("/callback", authHandlers.CallbackHandler)
-					r.Get("/logout", authHandlers.LogoutHandler)
-					r.Get("/logged-out", authHandlers.LoggedOutHandler)
-					r.Post("/back-channel-logout", authHandlers.BackChannelLogoutHandler)
-					r.Get("/user", authHandlers.UserInfoHandler) // For debugging
-				})
-
-				// Protected routes (authentication required)
-				r.Group(func(r chi.Router) {
-					r.Use(authService.RequireAuth) // Apply authentication middleware
-
-					// Serve static files with base path awareness
-					staticPrefix := cfg.BasePath + "static/"
-					r.Handle("/static/*", http.StripPrefix(staticPrefix, staticServe))
-
-					// Define protected routes
-					r.Get("/", h.HomeHandler)
-					r.Get("/api/media", h.MediaHandler)
-					r.Get("/api/epg", h.EpgHandler)
-					r.Post("/api/send", h.SendHandler)
-					r.Get("/search", h.SearchHandler)
-					r.Post("/search", h.SearchHandler)
-					r.Get("/refresh", h.RefreshHandler)
-				})
-			} else {
-				// No authentication - all routes are public
-				// Serve static files with base path awareness
-				staticPrefix := cfg.BasePath + "static/"
-				r.Handle("/static/*", http.StripPrefix(staticPrefix, staticServe))
-
-				// Define public routes
-				r.Get("/", h.HomeHandler)
-				r.Get("/api/media", h.MediaHandler)
-				r.Get("/api/epg", h.EpgHandler)
-				r.Post("/api/send", h.SendHandler)
-				r.Get("/search", h.SearchHandler)
-				r.Post("/search", h.SearchHandler)
-				r.Get("/refresh", h.RefreshHandler)
-			}
+			routes(r, cfg, h, authService, authHandlers, staticServe)
 		})
 	}
 
 	// Start server
-	logger.Info("Starting server", "port", cfg.Port)
-	err = http.ListenAndServe(":"+cfg.Port, r)
-	if err != nil {
-		logger.Error("Failed to start server", "error", err)
-		os.Exit(1)
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: r}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		logger.Info("Starting server", "port", cfg.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Failed to start server", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	// Finish in-flight requests and save the caches before exiting
+	<-ctx.Done()
+	logger.Info("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Server did not shut down cleanly", "error", err)
 	}
+	h.Close()
 }
 
-// setupRoutes configures all application routes
-func setupRoutes(r chi.Router, cfg *config.Config, h *handlers.Handlers, authService *auth.AuthService, authHandlers *auth.AuthHandlers, staticDir string) {
+// routes configures all application routes
+func routes(r chi.Router, cfg *config.Config, h *handlers.Handlers, authService *auth.AuthService, authHandlers *auth.AuthHandlers, staticServe http.Handler) {
 	// Public routes (no authentication required)
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok","service":"go-media-control"}`))
-	})
+	r.Get("/health", h.HealthHandler)
+
+	// Serve static files with base path awareness
+	staticPrefix := cfg.BasePath + "static/"
+	static := http.StripPrefix(staticPrefix, staticServe)
 
 	if !cfg.DisableAuth {
 		// Authentication routes (no auth required)
@@ -199,35 +120,57 @@ func setupRoutes(r chi.Router, cfg *config.Config, h *handlers.Handlers, authSer
 			r.Get("/callback", authHandlers.CallbackHandler)
 			r.Get("/logout", authHandlers.LogoutHandler)
 			r.Get("/logged-out", authHandlers.LoggedOutHandler)
+			r.Post("/back-channel-logout", authHandlers.BackChannelLogoutHandler)
 			r.Get("/user", authHandlers.UserInfoHandler) // For debugging
 		})
 
 		// Protected routes (authentication required)
 		r.Group(func(r chi.Router) {
 			r.Use(authService.RequireAuth) // Apply authentication middleware
+			r.Handle("/static/*", static)
 
-			// Serve static files with base path awareness
-			staticPrefix := cfg.BasePath + "static/"
-			r.Handle("/static/*", http.StripPrefix(staticPrefix, http.FileServer(http.Dir(staticDir))))
-
-			// Define protected routes
-			r.Get("/", h.HomeHandler)
-			r.Get("/api/media", h.MediaHandler)
-			r.Post("/api/send", h.SendHandler)
-			r.Post("/search", h.SearchHandler)
-			r.Get("/refresh", h.RefreshHandler)
+			// Define protected routes, each requiring a permission
+			r.Group(func(r chi.Router) {
+				r.Use(authService.RequirePermission(auth.PermView))
+				viewRoutes(r, h)
+				r.Get("/tokens", h.TokensHandler)
+				r.Post("/tokens", h.CreateTokenHandler)
+				r.Delete("/tokens/{tokenID}", h.RevokeTokenHandler)
+			})
+			// Handlers check the chosen target as well
+			r.With(authService.RequirePermission(auth.PermSend)).Post("/api/player", h.PlayerControlHandler)
+			r.With(authService.RequirePermission(auth.PermSend)).Post("/api/send", h.SendHandler)
+			r.With(authService.RequirePermission(auth.PermRefresh)).Get("/refresh", h.RefreshHandler)
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(authService.RequirePermission(auth.PermAdmin))
+				r.Get("/sessions", h.SessionsHandler)
+				r.Delete("/sessions/{handle}", h.RevokeSessionHandler)
+			})
 		})
 	} else {
 		// No authentication - all routes are public
-		// Serve static files with base path awareness
-		staticPrefix := cfg.BasePath + "static/"
-		r.Handle("/static/*", http.StripPrefix(staticPrefix, http.FileServer(http.Dir(staticDir))))
-
-		// Define public routes
-		r.Get("/", h.HomeHandler)
-		r.Get("/api/media", h.MediaHandler)
+		r.Handle("/static/*", static)
+		viewRoutes(r, h)
+		r.Post("/api/player", h.PlayerControlHandler)
 		r.Post("/api/send", h.SendHandler)
-		r.Post("/search", h.SearchHandler)
 		r.Get("/refresh", h.RefreshHandler)
 	}
 }
+
+// viewRoutes registers the pages and read-only API, shared by both auth modes
+func viewRoutes(r chi.Router, h *handlers.Handlers) {
+	r.Get("/", h.HomeHandler)
+	r.Get("/movies", h.MoviesHandler)
+	r.Get("/series", h.SeriesHandler)
+	r.Get("/series/{seriesID}", h.SeriesDetailHandler)
+	r.Get("/status", h.StatusHandler)
+	r.Get("/api/media", h.MediaHandler)
+	r.Get("/api/epg", h.EpgHandler)
+	r.Get("/api/account-warnings", h.AccountWarningsHandler)
+	r.Get("/api/formats", h.FormatsHandler)
+	r.Get("/api/targets", h.TargetsHandler)
+	r.Get("/api/player", h.PlayerHandler)
+	r.Get("/api/vod-info", h.VodInfoHandler)
+	r.Get("/search", h.SearchHandler)
+	r.Post("/search", h.SearchHandler)
+}
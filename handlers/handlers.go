@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -10,38 +11,139 @@ import (
 	"sync"
 	"time"
 
+	"github.com/git-saj/go-media-control/internal/auth"
+	"github.com/git-saj/go-media-control/internal/cache"
 	"github.com/git-saj/go-media-control/internal/config"
 	"github.com/git-saj/go-media-control/internal/discord"
+	"github.com/git-saj/go-media-control/internal/m3u"
+	"github.com/git-saj/go-media-control/internal/sink"
 	"github.com/git-saj/go-media-control/internal/xtream"
 	"github.com/git-saj/go-media-control/templates"
+	"github.com/go-chi/chi/v5"
 )
 
 // Handlers holds dependencies for HTTP handlers
 type Handlers struct {
-	logger        *slog.Logger
-	xtreamClient  *xtream.Client
-	discordClient *discord.WebhookClient
-	commandPrefix string
-	basePath      string
-	cfg           *config.Config
-	hasAuth       bool
+	logger      *slog.Logger
+	sources     *xtream.MultiClient
+	targets     []sink.Sink                  // Discord targets then other sinks; the first is the default
+	lastTargets *cache.Keyed[string, string] // Last target each user sent to, by user subject
+	basePath    string
+	cfg         *config.Config
+	hasAuth     bool
+	policy      *auth.Policy       // Per-target send permissions; routes enforce the rest
+	tokens      *auth.TokenStore   // Personal API tokens; nil without authentication
+	sessions    *auth.SessionStore // Signed-in sessions; nil without authentication
+	cacheStore  *cache.Store       // Nil when cache snapshots are disabled
 }
 
+// lastTargetTTL is how long the target a user last sent to is remembered
+const lastTargetTTL = 90 * 24 * time.Hour
+
 // NewHandlers creates a new Handlers instance
 func NewHandlers(logger *slog.Logger, cfg *config.Config) *Handlers {
+	// Snapshots are optional, so a bad directory only costs the warm start
+	store, err := cache.NewStore(cfg.CacheDir)
+	if err != nil {
+		logger.Warn("Cache snapshots disabled", "dir", cfg.CacheDir, "error", err)
+	}
+
+	playlists := make([]xtream.ChannelSource, 0, len(cfg.M3UPlaylists))
+	for _, p := range cfg.M3UPlaylists {
+		playlist := m3u.NewPlaylist(cfg, p)
+		playlist.Persist(store)
+		playlists = append(playlists, playlist)
+	}
+
+	layout := embedLayout(cfg)
+	targets := make([]sink.Sink, 0, len(cfg.DiscordTargets)+len(cfg.Sinks))
+	for _, t := range cfg.DiscordTargets {
+		targets = append(targets, discord.NewWebhookClient(t, layout))
+	}
+	for _, sc := range cfg.Sinks {
+		s, err := sink.New(sc)
+		if err != nil {
+			logger.Error("Skipping sink", "sink", sc.Name, "error", err)
+			continue
+		}
+		targets = append(targets, s)
+	}
+
 	h := &Handlers{
-		logger:        logger,
-		xtreamClient:  xtream.NewClient(cfg),
-		discordClient: discord.NewWebhookClient(cfg.DiscordWebhook),
-		commandPrefix: cfg.CommandPrefix,
-		basePath:      cfg.BasePath,
-		cfg:           cfg,
-		hasAuth:       !cfg.DisableAuth,
-	}
-	h.logger.Info("Handlers initialized", "xtream_baseurl", cfg.XtreamBaseURL, "base_path", cfg.BasePath, "has_auth", h.hasAuth, "disable_epg_prefetch", h.cfg.DisableEpgPrefetch)
+		logger:      logger,
+		sources:     xtream.NewMultiClient(cfg, playlists...),
+		targets:     targets,
+		lastTargets: cache.NewKeyed[string, string](1000),
+		basePath:    cfg.BasePath,
+		cfg:         cfg,
+		hasAuth:     !cfg.DisableAuth,
+		policy:      auth.NewPolicy(cfg),
+		cacheStore:  store,
+	}
+	for _, c := range h.sources.Clients() {
+		c.Persist(store)
+	}
+	h.lastTargets.Persist(store, "send-targets")
+	go store.Run(time.Minute)
+
+	providers := make([]string, 0, len(cfg.XtreamProviders))
+	for _, p := range cfg.XtreamProviders {
+		providers = append(providers, p.Name+"="+p.BaseURL)
+	}
+	for _, p := range cfg.M3UPlaylists {
+		providers = append(providers, p.Name+"="+p.Source)
+	}
+	h.logger.Info("Handlers initialized", "sources", strings.Join(providers, ","), "base_path", cfg.BasePath, "has_auth", h.hasAuth, "disable_epg_prefetch", h.cfg.DisableEpgPrefetch, "cache_dir", cfg.CacheDir)
 	return h
 }
 
+// Close saves the cache snapshots so the next start is warm
+func (h *Handlers) Close() {
+	h.cacheStore.Flush()
+}
+
+// decorateWithEPG fetches the guide of each item concurrently and sets its
+// current and next programme, with titles shortened to fit the card
+func (h *Handlers) decorateWithEPG(ctx context.Context, items []xtream.MediaItem) {
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range items {
+		wg.Add(1)
+		go func(item *xtream.MediaItem) {
+			defer wg.Done()
+			epg, _, err := h.sources.GetEpgForStream(ctx, item.Provider, item.StreamID)
+			if err != nil {
+				h.logger.Warn("Failed to fetch EPG for stream", "stream_id", item.StreamID, "error", err)
+				return
+			}
+			now := time.Now().Unix()
+			for _, program := range epg {
+				if now >= program.Start && now <= program.End {
+					current := program
+					current.Title = shortTitle(current.Title)
+					item.CurrentProgram = &current
+				} else if now < program.Start && item.NextProgram == nil {
+					next := program
+					next.Title = shortTitle(next.Title)
+					item.NextProgram = &next
+				}
+			}
+			h.logger.Debug("Fetched EPG", "stream_id", item.StreamID, "program_count", len(epg),
+				"now", item.CurrentProgram != nil, "next", item.NextProgram != nil)
+		}(&items[i])
+	}
+	wg.Wait()
+	h.logger.Info("EPG fetch completed", "channels", len(items), "duration", time.Since(start))
+}
+
+// shortTitle cuts a programme title to 20 bytes for the channel cards
+func shortTitle(title string) string {
+	if len(title) > 20 {
+		return title[:20] + "..."
+	}
+	return title
+}
+
 // paginate slices a channel list based on page and limit
 func paginate(channels []xtream.MediaItem, page, limit int) ([]xtream.MediaItem, int) {
 	total := len(channels)
@@ -60,7 +162,7 @@ func paginate(channels []xtream.MediaItem, page, limit int) ([]xtream.MediaItem,
 
 // HomeHandler serves the main UI at / with pagination
 func (h *Handlers) HomeHandler(w http.ResponseWriter, r *http.Request) {
-	media, err := h.xtreamClient.GetLiveStreams()
+	media, err := h.sources.GetLiveStreams(r.Context())
 	if err != nil {
 		h.logger.Error("Failed to fetch media for home", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -81,48 +183,9 @@ func (h *Handlers) HomeHandler(w http.ResponseWriter, r *http.Request) {
 
 	paginated, total := paginate(media, page, limit)
 
-	// Fetch EPG for paginated channels concurrently
-	var wg sync.WaitGroup
-	for i := range paginated {
-		wg.Add(1)
-		go func(idx int) {
-			defer wg.Done()
-			now := time.Now().Unix()
-			epg, _, err := h.xtreamClient.GetEpgForStream(paginated[idx].StreamID)
-			if err != nil {
-				h.logger.Warn("Failed to fetch EPG for stream", "stream_id", paginated[idx].StreamID, "error", err)
-				return
-			}
-			h.logger.Info("Fetched EPG", "stream_id", paginated[idx].StreamID, "program_count", len(epg))
-			for _, program := range epg {
-				if now >= program.Start && now <= program.End {
-					current := program
-					if len(current.Title) > 20 {
-						current.Title = current.Title[:20] + "..."
-					}
-					paginated[idx].CurrentProgram = &current
-				} else if now < program.Start && paginated[idx].NextProgram == nil {
-					next := program
-					if len(next.Title) > 20 {
-						next.Title = next.Title[:20] + "..."
-					}
-					paginated[idx].NextProgram = &next
-				}
-			}
-			if paginated[idx].CurrentProgram != nil {
-				h.logger.Info("Set current program", "stream_id", paginated[idx].StreamID, "title", paginated[idx].CurrentProgram.Title)
-			}
-			if paginated[idx].NextProgram != nil {
-				h.logger.Info("Set next program", "stream_id", paginated[idx].StreamID, "title", paginated[idx].NextProgram.Title)
-			}
-			if paginated[idx].CurrentProgram == nil && paginated[idx].NextProgram == nil {
-				h.logger.Info("No current or next program found", "stream_id", paginated[idx].StreamID)
-			}
-		}(i)
-	}
-	wg.Wait()
+	h.decorateWithEPG(r.Context(), paginated)
 
-	categories, err := h.xtreamClient.GetCategories()
+	categories, err := h.sources.GetCategories(r.Context())
 	if err != nil {
 		h.logger.Error("Failed to fetch categories", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -132,10 +195,10 @@ func (h *Handlers) HomeHandler(w http.ResponseWriter, r *http.Request) {
 	// Check if this is an HTMX request for partial rendering
 	isHTMX := r.Header.Get("HX-Request") == "true"
 	if isHTMX {
-		templates.Results(paginated, page, limit, total, h.basePath, "", "").Render(r.Context(), w)
+		templates.Results(paginated, page, limit, total, h.basePath, "", "", xtream.StreamTypeLive).Render(r.Context(), w)
 	} else {
 
-		templates.Home(paginated, page, limit, total, h.basePath, h.hasAuth, categories, "", "").Render(r.Context(), w)
+		templates.Home(paginated, page, limit, total, h.basePath, h.hasAuth, categories, "", "", xtream.StreamTypeLive).Render(r.Context(), w)
 	}
 }
 
@@ -144,26 +207,31 @@ func (h *Handlers) SearchHandler(w http.ResponseWriter, r *http.Request) {
 	totalStart := time.Now()
 	h.logger.Info("SearchHandler started", "method", r.Method, "query", r.URL.Query().Get("query"))
 
-	var query, pageStr, limitStr, categoryStr string
+	var query, pageStr, limitStr, categoryStr, mediaType string
 	if r.Method == "GET" {
 		query = r.URL.Query().Get("query")
 		pageStr = r.URL.Query().Get("page")
 		limitStr = r.URL.Query().Get("limit")
 		categoryStr = r.URL.Query().Get("category")
+		mediaType = r.URL.Query().Get("type")
 	} else {
 		query = r.FormValue("query")
 		pageStr = r.FormValue("page")
 		limitStr = r.FormValue("limit")
 		categoryStr = r.FormValue("category")
+		mediaType = r.FormValue("type")
+	}
+	if mediaType != xtream.StreamTypeMovie && mediaType != xtream.StreamTypeSeries {
+		mediaType = xtream.StreamTypeLive
 	}
 
-	media, err := h.xtreamClient.GetLiveStreams()
+	media, err := h.mediaForType(r.Context(), mediaType)
 	if err != nil {
-		h.logger.Error("Failed to fetch media for search", "error", err)
+		h.logger.Error("Failed to fetch media for search", "type", mediaType, "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	h.logger.Info("GetLiveStreams completed", "duration", time.Since(totalStart))
+	h.logger.Info("Fetching media completed", "type", mediaType, "duration", time.Since(totalStart))
 	// Filter channels
 	filterStart := time.Now()
 	var filtered []xtream.MediaItem
@@ -203,46 +271,18 @@ func (h *Handlers) SearchHandler(w http.ResponseWriter, r *http.Request) {
 
 	paginated, total := paginate(filtered, page, limit)
 
-	// Fetch EPG for paginated channels concurrently
-	epgStart := time.Now()
-	var wg sync.WaitGroup
-	for i := range paginated {
-		wg.Add(1)
-		go func(idx int) {
-			defer wg.Done()
-			now := time.Now().Unix()
-			epg, _, err := h.xtreamClient.GetEpgForStream(paginated[idx].StreamID)
-			if err != nil {
-				h.logger.Warn("Failed to fetch EPG for stream", "stream_id", paginated[idx].StreamID, "error", err)
-				return
-			}
-			for _, program := range epg {
-				if now >= program.Start && now <= program.End {
-					current := program
-					if len(current.Title) > 20 {
-						current.Title = current.Title[:20] + "..."
-					}
-					paginated[idx].CurrentProgram = &current
-				} else if now < program.Start && paginated[idx].NextProgram == nil {
-					next := program
-					if len(next.Title) > 20 {
-						next.Title = next.Title[:20] + "..."
-					}
-					paginated[idx].NextProgram = &next
-				}
-			}
-		}(i)
+	// Movies and series have no EPG
+	if mediaType == xtream.StreamTypeLive {
+		h.decorateWithEPG(r.Context(), paginated)
 	}
-	wg.Wait()
-	h.logger.Info("EPG fetch completed", "duration", time.Since(epgStart))
 
 	// Check if this is an HTMX request for partial rendering
 	isHTMX := r.Header.Get("HX-Request") == "true"
 	if isHTMX {
-		templates.Results(paginated, page, limit, total, h.basePath, query, categoryStr).Render(r.Context(), w)
+		templates.Results(paginated, page, limit, total, h.basePath, query, categoryStr, mediaType).Render(r.Context(), w)
 	} else {
 		catStart := time.Now()
-		categories, err := h.xtreamClient.GetCategories()
+		categories, err := h.categoriesForType(r.Context(), mediaType)
 		if err != nil {
 			h.logger.Error("Failed to fetch categories for search", "error", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -251,7 +291,7 @@ func (h *Handlers) SearchHandler(w http.ResponseWriter, r *http.Request) {
 		h.logger.Info("GetCategories completed", "duration", time.Since(catStart))
 
 		renderStart := time.Now()
-		templates.Home(paginated, page, limit, total, h.basePath, h.hasAuth, categories, query, categoryStr).Render(r.Context(), w)
+		templates.Home(paginated, page, limit, total, h.basePath, h.hasAuth, categories, query, categoryStr, mediaType).Render(r.Context(), w)
 		h.logger.Info("Template render completed", "duration", time.Since(renderStart))
 		h.logger.Info("SearchHandler total duration", "duration", time.Since(totalStart))
 	}
@@ -259,14 +299,10 @@ func (h *Handlers) SearchHandler(w http.ResponseWriter, r *http.Request) {
 
 // RefreshCacheHandler clears the cache and returns refreshed results
 func (h *Handlers) RefreshHandler(w http.ResponseWriter, r *http.Request) {
-	// Clear the media cache
-	h.xtreamClient.Cache.Clear()
-	// Clear the EPG cache
-	h.xtreamClient.EpgCache.Clear()
-	// Reset EPG fetch time to force refetch
-	h.xtreamClient.EpgFetchTime = time.Time{}
-
-	media, err := h.xtreamClient.GetLiveStreams()
+	// Clear the media, EPG, movie and series caches of every provider
+	h.sources.ClearCache()
+
+	media, err := h.sources.GetLiveStreams(r.Context())
 	if err != nil {
 		h.logger.Error("Failed to fetch media", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -287,53 +323,135 @@ func (h *Handlers) RefreshHandler(w http.ResponseWriter, r *http.Request) {
 
 	paginated, total := paginate(media, page, limit)
 
-	// Fetch EPG for paginated channels concurrently
-	var wg sync.WaitGroup
-	for i := range paginated {
-		wg.Add(1)
-		go func(idx int) {
-			defer wg.Done()
-			now := time.Now().Unix()
-			epg, _, err := h.xtreamClient.GetEpgForStream(paginated[idx].StreamID)
-			if err != nil {
-				h.logger.Warn("Failed to fetch EPG for stream", "stream_id", paginated[idx].StreamID, "error", err)
-				return
-			}
-			h.logger.Info("Fetched EPG", "stream_id", paginated[idx].StreamID, "program_count", len(epg))
-			for _, program := range epg {
-				if now >= program.Start && now <= program.End {
-					current := program
-					if len(current.Title) > 20 {
-						current.Title = current.Title[:20] + "..."
-					}
-					paginated[idx].CurrentProgram = &current
-				} else if now < program.Start && paginated[idx].NextProgram == nil {
-					next := program
-					if len(next.Title) > 20 {
-						next.Title = next.Title[:20] + "..."
-					}
-					paginated[idx].NextProgram = &next
-				}
-			}
-			if paginated[idx].CurrentProgram != nil {
-				h.logger.Info("Set current program", "stream_id", paginated[idx].StreamID, "title", paginated[idx].CurrentProgram.Title)
-			}
-			if paginated[idx].NextProgram != nil {
-				h.logger.Info("Set next program", "stream_id", paginated[idx].StreamID, "title", paginated[idx].NextProgram.Title)
-			}
-			if paginated[idx].CurrentProgram == nil && paginated[idx].NextProgram == nil {
-				h.logger.Info("No current or next program found", "stream_id", paginated[idx].StreamID)
-			}
-		}(i)
+	h.decorateWithEPG(r.Context(), paginated)
+
+	templates.Results(paginated, page, limit, total, h.basePath, "", "", xtream.StreamTypeLive).Render(r.Context(), w)
+}
+
+// MoviesHandler serves the movies tab at /movies with pagination
+func (h *Handlers) MoviesHandler(w http.ResponseWriter, r *http.Request) {
+	h.catalogueHandler(w, r, xtream.StreamTypeMovie)
+}
+
+// SeriesHandler serves the series tab at /series with pagination
+func (h *Handlers) SeriesHandler(w http.ResponseWriter, r *http.Request) {
+	h.catalogueHandler(w, r, xtream.StreamTypeSeries)
+}
+
+// catalogueHandler renders a paginated, EPG-less catalogue of the given media type
+func (h *Handlers) catalogueHandler(w http.ResponseWriter, r *http.Request, mediaType string) {
+	media, err := h.mediaForType(r.Context(), mediaType)
+	if err != nil {
+		h.logger.Error("Failed to fetch catalogue", "type", mediaType, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Get page and limit from query params (default: page=1, limit=15)
+	pageStr := r.URL.Query().Get("page")
+	limitStr := r.URL.Query().Get("limit")
+	page, _ := strconv.Atoi(pageStr)
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(limitStr)
+	if limit < 1 {
+		limit = 15
+	}
+
+	paginated, total := paginate(media, page, limit)
+
+	// Check if this is an HTMX request for partial rendering
+	isHTMX := r.Header.Get("HX-Request") == "true"
+	if isHTMX {
+		templates.Results(paginated, page, limit, total, h.basePath, "", "", mediaType).Render(r.Context(), w)
+		return
+	}
+
+	categories, err := h.categoriesForType(r.Context(), mediaType)
+	if err != nil {
+		h.logger.Error("Failed to fetch categories", "type", mediaType, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	templates.Home(paginated, page, limit, total, h.basePath, h.hasAuth, categories, "", "", mediaType).Render(r.Context(), w)
+}
+
+// mediaForType returns the full catalogue for a media type
+func (h *Handlers) mediaForType(ctx context.Context, mediaType string) ([]xtream.MediaItem, error) {
+	switch mediaType {
+	case xtream.StreamTypeMovie:
+		return h.sources.GetVodStreams(ctx)
+	case xtream.StreamTypeSeries:
+		return h.sources.GetSeries(ctx)
+	default:
+		return h.sources.GetLiveStreams(ctx)
+	}
+}
+
+// categoriesForType returns the categories for a media type
+func (h *Handlers) categoriesForType(ctx context.Context, mediaType string) ([]xtream.Category, error) {
+	switch mediaType {
+	case xtream.StreamTypeMovie:
+		return h.sources.GetVodCategories(ctx)
+	case xtream.StreamTypeSeries:
+		return h.sources.GetSeriesCategories(ctx)
+	default:
+		return h.sources.GetCategories(ctx)
+	}
+}
+
+// SeriesDetailHandler serves the season and episode browser at /series/{seriesID}
+func (h *Handlers) SeriesDetailHandler(w http.ResponseWriter, r *http.Request) {
+	seriesIDStr := chi.URLParam(r, "seriesID")
+	seriesID, err := strconv.Atoi(seriesIDStr)
+	if err != nil {
+		h.logger.Warn("Invalid series_id", "series_id", seriesIDStr, "error", err)
+		http.Error(w, "Invalid series ID", http.StatusBadRequest)
+		return
 	}
-	wg.Wait()
 
-	templates.Results(paginated, page, limit, total, h.basePath, "", "").Render(r.Context(), w)
+	provider := r.URL.Query().Get("provider")
+	client, ok := h.sources.Client(provider)
+	if !ok {
+		h.logger.Warn("Unknown provider", "provider", provider)
+		http.Error(w, "Unknown provider", http.StatusNotFound)
+		return
+	}
+
+	info, err := client.FetchSeriesInfo(r.Context(), seriesID)
+	if err != nil {
+		h.logger.Error("Failed to fetch series info", "series_id", seriesID, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Default to the first season when none (or an unknown one) is requested
+	season, _ := strconv.Atoi(r.URL.Query().Get("season"))
+	var selected *xtream.Season
+	for i := range info.Seasons {
+		if info.Seasons[i].Number == season {
+			selected = &info.Seasons[i]
+			break
+		}
+	}
+	if selected == nil && len(info.Seasons) > 0 {
+		selected = &info.Seasons[0]
+	}
+
+	isHTMX := r.Header.Get("HX-Request") == "true"
+	if isHTMX && selected != nil {
+		templates.EpisodeCards(selected.Episodes, info.Provider, h.basePath).Render(r.Context(), w)
+		return
+	}
+
+	templates.SeriesDetail(*info, selected, h.basePath, h.hasAuth).Render(r.Context(), w)
 }
 
 // MediaHandler handles GET /api/media requests
 func (h *Handlers) MediaHandler(w http.ResponseWriter, r *http.Request) {
-	media, err := h.xtreamClient.GetLiveStreams()
+	media, err := h.sources.GetLiveStreams(r.Context())
 	if err != nil {
 		h.logger.Error("Failed to fetch media", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -350,7 +468,13 @@ func (h *Handlers) MediaHandler(w http.ResponseWriter, r *http.Request) {
 
 // SendRequest represents the expected JSON body for /api/send
 type SendRequest struct {
-	ChannelID int `json:"channel_id"`
+	ChannelID int    `json:"channel_id"`
+	Type      string `json:"type,omitempty"`     // "live" (default), "movie", "series" (episode ID) or "timeshift"
+	Start     int64  `json:"start,omitempty"`    // Programme start for "timeshift" (unix seconds)
+	End       int64  `json:"end,omitempty"`      // Programme end for "timeshift" (unix seconds)
+	Format    string `json:"format,omitempty"`   // Live output format ("ts" or "m3u8"), defaults to OUTPUT_FORMAT
+	Provider  string `json:"provider,omitempty"` // Provider serving the channel, defaults to the primary
+	Target    string `json:"target,omitempty"`   // Target or sink to send to, defaults to the first
 }
 
 // SendHandler handles POST /api/send requests
@@ -362,33 +486,232 @@ func (h *Handlers) SendHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	streamURL, ok := h.xtreamClient.GetStreamURL(req.ChannelID)
+	src, ok := h.sources.Source(req.Provider)
 	if !ok {
-		h.logger.Warn("Channel not found", "channel_id", req.ChannelID)
-		http.Error(w, "Channel not found", http.StatusNotFound)
+		h.logger.Warn("Unknown provider", "provider", req.Provider)
+		http.Error(w, "Unknown provider", http.StatusNotFound)
+		return
+	}
+	target, ok := h.target(r.Context(), req.Target)
+	if !ok {
+		h.logger.Warn("Unknown send target", "target", req.Target)
+		http.Error(w, "Unknown send target", http.StatusNotFound)
+		return
+	}
+	if !h.allowed(r.Context(), auth.SendTo(target.Name())) {
+		h.logger.Warn("Send to target denied", "target", target.Name(), "user", userKey(r.Context()))
+		http.Error(w, "You don't have permission to send to "+target.Name()+".", http.StatusForbidden)
 		return
 	}
 
-	h.logger.Info("Sending command", "channel", req.ChannelID, "url", streamURL)
-	err := h.discordClient.Send(fmt.Sprintf("%s %s", h.commandPrefix, streamURL))
-	if err != nil {
-		h.logger.Error("Failed to send Discord message", "error", err)
-		http.Error(w, "Failed to send command", http.StatusInternalServerError)
+	// Movies, series and catch-up are only offered by Xtream providers
+	client, isXtream := h.sources.Client(src.Name())
+	if req.Type != "" && req.Type != xtream.StreamTypeLive && !isXtream {
+		h.logger.Warn("Stream type not offered by source", "type", req.Type, "provider", src.Name())
+		http.Error(w, "Stream type not offered by this source", http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	var streamURL string
+	provider := src.Name()
+	switch req.Type {
+	case "", xtream.StreamTypeLive:
+		if req.Format != "" {
+			if err := src.CheckFormat(r.Context(), req.Format); err != nil {
+				h.logger.Warn("Rejected output format", "format", req.Format, "error", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		// Live channels fall over to another provider when this one is failing
+		streamURL, provider, ok = h.sources.LiveStreamURL(r.Context(), src.Name(), req.ChannelID, req.Format)
+	case xtream.StreamTypeMovie:
+		streamURL, ok = client.GetMovieURL(req.ChannelID)
+	case xtream.StreamTypeSeries:
+		streamURL, ok = client.GetEpisodeURL(req.ChannelID)
+	case xtream.StreamTypeTimeshift:
+		streamURL, ok = client.GetTimeshiftURL(req.ChannelID, req.Start, req.End)
+		if !ok {
+			h.logger.Warn("Programme not available for catch-up", "channel_id", req.ChannelID, "start", req.Start, "end", req.End)
+			http.Error(w, "Programme not available for catch-up", http.StatusNotFound)
+			return
+		}
+	default:
+		h.logger.Warn("Unknown stream type", "type", req.Type)
+		http.Error(w, "Unknown stream type", http.StatusBadRequest)
+		return
+	}
+	if !ok {
+		h.logger.Warn("Channel not found", "channel_id", req.ChannelID, "type", req.Type, "provider", src.Name())
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	h.logger.Info("Sending command", "channel", req.ChannelID, "type", req.Type, "format", req.Format, "provider", provider, "target", target.Name(), "url", streamURL)
+	np := h.nowPlaying(r.Context(), req, src, client, provider)
+	result, err := target.Send(r.Context(), sink.Message{StreamURL: streamURL, NowPlaying: np})
+	if result != sink.Dropped {
+		h.lastTargets.Set(userKey(r.Context()), target.Name(), lastTargetTTL)
+	}
+	response := struct {
+		Status sink.Result `json:"status"`
+		Error  string      `json:"error,omitempty"`
+	}{Status: result}
+	status := http.StatusOK
+	switch result {
+	case sink.Queued:
+		// Held back by a Discord rate limit or retry; it is still sent in order
+		h.logger.Info("Message queued", "channel", req.ChannelID, "target", target.Name())
+		status = http.StatusAccepted
+	case sink.Dropped:
+		// Sink errors can carry service details; they are logged, not shown
+		h.logger.Error("Failed to send message", "target", target.Name(), "error", err)
+		response.Error = "Failed to send to " + target.Name() + ", see the logs for details"
+		status = http.StatusBadGateway
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode send response", "error", err)
+	}
+}
+
+// target looks up a send target by name; an empty name selects the first the
+// user may send to
+func (h *Handlers) target(ctx context.Context, name string) (sink.Sink, bool) {
+	if name == "" {
+		if targets := h.allowedTargets(ctx); len(targets) > 0 {
+			return targets[0], true
+		}
+		return nil, false
+	}
+	for _, t := range h.targets {
+		if t.Name() == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// allowedTargets returns the targets the user may send to, in picker order
+func (h *Handlers) allowedTargets(ctx context.Context) []sink.Sink {
+	var targets []sink.Sink
+	for _, t := range h.targets {
+		if h.allowed(ctx, auth.SendTo(t.Name())) {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// allowed reports whether the signed-in user holds the permission. Without
+// authentication there is no user and everything is allowed.
+func (h *Handlers) allowed(ctx context.Context, perm auth.Permission) bool {
+	user, ok := auth.GetUserFromContext(ctx)
+	return !ok || h.policy.Allows(user, perm)
+}
+
+// userKey identifies the signed-in user for per-user preferences. Without
+// authentication everyone shares the empty key.
+func userKey(ctx context.Context) string {
+	if user, ok := auth.GetUserFromContext(ctx); ok {
+		return user.Subject
+	}
+	return ""
+}
+
+// nowPlaying describes a sent stream for the sink's message. Details that cannot
+// be found, such as the guide of a channel without EPG, are left out.
+func (h *Handlers) nowPlaying(ctx context.Context, req SendRequest, src xtream.ChannelSource, client *xtream.Client, provider string) sink.NowPlaying {
+	np := sink.NowPlaying{Type: req.Type, Provider: provider}
+	if np.Type == "" {
+		np.Type = xtream.StreamTypeLive
+	}
+	if user, ok := auth.GetUserFromContext(ctx); ok {
+		np.User = user.Name
+		if np.User == "" {
+			np.User = user.PreferredUsername
+		}
+	}
+
+	switch np.Type {
+	case xtream.StreamTypeMovie:
+		if items, err := client.GetVodStreams(ctx); err == nil {
+			if item, ok := findItem(items, req.ChannelID); ok {
+				np.Title, np.Logo = item.Name, item.Logo
+			}
+		}
+	case xtream.StreamTypeSeries:
+		np.Title, np.Logo, _ = client.EpisodeLabel(req.ChannelID)
+	default:
+		// Live and catch-up sends show the channel and its guide
+		if items, err := src.GetLiveStreams(ctx); err == nil {
+			if item, ok := findItem(items, req.ChannelID); ok {
+				np.Title, np.Logo = item.Name, item.Logo
+			}
+		}
+		epg, _, err := h.sources.GetEpgForStream(ctx, src.Name(), req.ChannelID)
+		if err != nil {
+			h.logger.Warn("Failed to fetch EPG for message", "stream_id", req.ChannelID, "error", err)
+			break
+		}
+		if np.Type == xtream.StreamTypeTimeshift {
+			for _, program := range epg {
+				if program.Start == req.Start {
+					np.Current, np.Replay = sinkProgramme(program), true
+					break
+				}
+			}
+		} else {
+			now := time.Now().Unix()
+			for _, program := range epg {
+				if now >= program.Start && now <= program.End {
+					np.Current = sinkProgramme(program)
+				} else if now < program.Start && np.Next == nil {
+					np.Next = sinkProgramme(program)
+				}
+			}
+		}
+	}
+	if np.Title == "" {
+		np.Title = fmt.Sprintf("Stream %d", req.ChannelID)
+	}
+	return np
+}
+
+// findItem finds a media item by stream ID
+func findItem(items []xtream.MediaItem, streamID int) (xtream.MediaItem, bool) {
+	for _, item := range items {
+		if item.StreamID == streamID {
+			return item, true
+		}
+	}
+	return xtream.MediaItem{}, false
+}
+
+// sinkProgramme converts a guide entry for a sent message
+func sinkProgramme(program xtream.EpgListing) *sink.Programme {
+	return &sink.Programme{
+		Title: program.Title,
+		Start: time.Unix(program.Start, 0),
+		End:   time.Unix(program.End, 0),
+	}
+}
+
+// embedLayout returns the configured Discord embed layout, or nil when embeds are disabled
+func embedLayout(cfg *config.Config) *discord.EmbedLayout {
+	if !cfg.DiscordEmbeds {
+		return nil
+	}
+	return &discord.EmbedLayout{Fields: cfg.DiscordEmbedFields, Color: cfg.DiscordEmbedColor}
 }
 
 func (h *Handlers) ClearCacheHandler(w http.ResponseWriter, r *http.Request) {
-	// Clear media cache
-	h.xtreamClient.Cache.Clear()
-	// Clear EPG cache
-	h.xtreamClient.EpgCache.Clear()
-	// Clear categories cache if exists
-	h.xtreamClient.CategoryCache.Clear()
-	// Reset EPG fetch time
-	h.xtreamClient.EpgFetchTime = time.Time{}
+	// Clear media, EPG, movie and series caches of every provider
+	h.sources.ClearCache()
+	// Clear category caches
+	h.sources.ClearCategoryCache()
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Cache cleared"))
@@ -411,34 +734,355 @@ func (h *Handlers) EpgHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	epg, rawResponse, err := h.xtreamClient.GetEpgForStream(streamID)
+	src, ok := h.sources.Source(r.URL.Query().Get("provider"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("<p class='text-red-500'>Unknown provider</p>"))
+		return
+	}
+	// Catch-up is only offered by Xtream providers
+	client, isXtream := h.sources.Client(src.Name())
+
+	epg, rawResponse, err := h.sources.GetEpgForStream(r.Context(), src.Name(), streamID)
 	if err != nil {
 		h.logger.Error("Failed to fetch EPG", "stream_id", streamID, "error", err)
+		w.Header().Set("Content-Type", "text/html")
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("<p class='text-red-500'>Failed to load EPG: " + err.Error() + "</p><details><summary>Debug Info</summary><pre>" + rawResponse + "</pre></details>"))
+		// The error can hold the provider URL with its credentials; it is only logged
+		templates.EpgNotice("Failed to load EPG", "text-red-500", "").Render(r.Context(), w)
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/html")
 	if len(epg) == 0 {
 		if strings.Contains(rawResponse, "user_info") {
-			w.Write([]byte("<p class='text-gray-500'>EPG not supported by your Xtream provider.</p>"))
+			templates.EpgNotice("EPG not supported by your Xtream provider.", "text-gray-500", "").Render(r.Context(), w)
 		} else {
-			debugInfo := fmt.Sprintf("<p class='text-gray-500'>No EPG available.</p><details><summary>Debug Info</summary><pre>%s</pre></details>", rawResponse)
-			w.Write([]byte(debugInfo))
+			templates.EpgNotice("No EPG available.", "text-gray-500", rawResponse).Render(r.Context(), w)
 		}
 		return
 	}
 
-	// Build simple HTML for EPG listings
-	html := "<div class='mt-2'><button class='btn btn-xs btn-ghost float-right' hx-get='" + h.basePath + fmt.Sprintf("api/epg?stream_id=%d&close=true", streamID) + "' hx-target='#epg-" + strconv.Itoa(streamID) + "' hx-swap='innerHTML'>×</button><ul class='list-disc list-inside clear-both'>"
-	for _, program := range epg {
-		startTime := time.Unix(program.Start, 0).Format("15:04")
-		endTime := time.Unix(program.End, 0).Format("15:04")
-		html += fmt.Sprintf("<li><strong>%s</strong> (%s - %s): %s</li>",
-			program.Title, startTime, endTime, program.Description)
+	canReplay := func(program xtream.EpgListing) bool {
+		// Past programme inside the catch-up window: offer to send a timeshift URL
+		return isXtream && client.CanReplay(streamID, program)
+	}
+	templates.EpgList(epg, canReplay, src.Name(), streamID, h.basePath).Render(r.Context(), w)
+}
+
+// VodInfoHandler handles GET /api/vod-info?vod_id=XXX requests and returns HTML for HTMX
+func (h *Handlers) VodInfoHandler(w http.ResponseWriter, r *http.Request) {
+	vodIDStr := r.URL.Query().Get("vod_id")
+	vodID, err := strconv.Atoi(vodIDStr)
+	if err != nil {
+		h.logger.Warn("Invalid vod_id", "vod_id", vodIDStr, "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("<p class='text-red-500'>Invalid movie ID</p>"))
+		return
+	}
+
+	if r.URL.Query().Get("close") == "true" {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(""))
+		return
+	}
+
+	client, ok := h.sources.Client(r.URL.Query().Get("provider"))
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("<p class='text-red-500'>Unknown provider</p>"))
+		return
+	}
+
+	info, err := client.FetchVodInfo(r.Context(), vodID)
+	if err != nil {
+		h.logger.Error("Failed to fetch movie info", "vod_id", vodID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("<p class='text-red-500'>Failed to load movie info</p>"))
+		return
 	}
-	html += "</ul></div>"
 
 	w.Header().Set("Content-Type", "text/html")
-	w.Write([]byte(html))
+	templates.VodInfo(*info, vodID, h.basePath).Render(r.Context(), w)
+}
+
+// HealthHandler handles GET /health. It is public and polled by liveness
+// probes, so it only reports state already held in memory and never contacts
+// a provider; subscription details are on the authenticated /status page.
+func (h *Handlers) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	type providerHealth struct {
+		Name     string       `json:"name"`
+		Healthy  bool         `json:"healthy"`
+		EpgCache *cache.Stats `json:"epg_cache,omitempty"`
+	}
+	response := struct {
+		Status    string           `json:"status"`
+		Service   string           `json:"service"`
+		Providers []providerHealth `json:"providers"`
+	}{
+		Status:  "ok",
+		Service: "go-media-control",
+	}
+
+	for _, src := range h.sources.Sources() {
+		provider := providerHealth{Name: src.Name(), Healthy: src.Healthy()}
+		if client, isXtream := h.sources.Client(src.Name()); isXtream {
+			epgStats := client.EpgCache.Stats()
+			provider.EpgCache = &epgStats
+		}
+		response.Providers = append(response.Providers, provider)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode health response", "error", err)
+	}
+}
+
+// StatusHandler serves the subscription status page at /status
+func (h *Handlers) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	accounts := h.sources.Accounts(r.Context())
+	for _, account := range accounts {
+		if account.Err != nil {
+			h.logger.Error("Failed to fetch account info", "provider", account.Provider, "error", account.Err)
+		}
+	}
+
+	templates.Status(accounts, h.accountWarnings(accounts), h.basePath, h.hasAuth).Render(r.Context(), w)
+}
+
+// AccountWarningsHandler handles GET /api/account-warnings and returns an HTML banner for HTMX
+func (h *Handlers) AccountWarningsHandler(w http.ResponseWriter, r *http.Request) {
+	accounts := h.sources.Accounts(r.Context())
+	warnings := h.accountWarnings(accounts)
+	for _, account := range accounts {
+		if account.Err != nil {
+			h.logger.Warn("Failed to fetch account info for warnings", "provider", account.Provider, "error", account.Err)
+			warnings = append(warnings, providerLabel(accounts, account.Provider)+"Could not reach the Xtream provider to check the subscription")
+		}
+	}
+
+	templates.AccountWarnings(warnings, h.basePath).Render(r.Context(), w)
+}
+
+// accountWarnings collects the subscription warnings of every provider
+func (h *Handlers) accountWarnings(accounts []xtream.ProviderAccount) []string {
+	var warnings []string
+	for _, account := range accounts {
+		if account.Err != nil {
+			continue
+		}
+		for _, warning := range account.Info.Warnings(h.cfg.AccountExpiryWarningDays, time.Now()) {
+			warnings = append(warnings, providerLabel(accounts, account.Provider)+warning)
+		}
+	}
+	return warnings
+}
+
+// providerLabel prefixes messages with the provider name when there is more than one
+func providerLabel(accounts []xtream.ProviderAccount, provider string) string {
+	if len(accounts) < 2 {
+		return ""
+	}
+	return provider + ": "
+}
+
+// FormatsHandler handles GET /api/formats and returns the output format selector for HTMX
+func (h *Handlers) FormatsHandler(w http.ResponseWriter, r *http.Request) {
+	primary := h.sources.Primary()
+	if primary == nil {
+		// Playlists are sent as listed, so there is no format to pick
+		return
+	}
+	templates.FormatSelect(primary.AllowedFormats(r.Context()), primary.DefaultFormat()).Render(r.Context(), w)
+}
+
+// TargetsHandler handles GET /api/targets and returns the send target selector
+// for HTMX, preselecting the target the user last sent to
+func (h *Handlers) TargetsHandler(w http.ResponseWriter, r *http.Request) {
+	targets := h.allowedTargets(r.Context())
+	if len(targets) < 2 {
+		// Nothing to pick; sends go to the only target
+		return
+	}
+	names := make([]string, 0, len(targets))
+	for _, t := range targets {
+		names = append(names, t.Name())
+	}
+	selected, _ := h.lastTargets.Get(userKey(r.Context()))
+	templates.TargetSelect(names, selected).Render(r.Context(), w)
+}
+
+// PlayerRequest represents the expected JSON body for POST /api/player
+type PlayerRequest struct {
+	Target string      `json:"target,omitempty"` // Player target, defaults like SendRequest.Target
+	Action sink.Action `json:"action"`           // "stop", "pause" (toggle) or "volume"
+	Value  int         `json:"value,omitempty"`  // Volume in percent for "volume"
+}
+
+// PlayerHandler handles GET /api/player and returns the playback state of the
+// selected target for HTMX, or nothing when it is not a player
+func (h *Handlers) PlayerHandler(w http.ResponseWriter, r *http.Request) {
+	player, ok := h.player(r.Context(), r.URL.Query().Get("target"))
+	if !ok {
+		return
+	}
+	h.renderPlayer(w, r, player, "")
+}
+
+// PlayerControlHandler handles POST /api/player, applying a playback control
+// and returning the updated state
+func (h *Handlers) PlayerControlHandler(w http.ResponseWriter, r *http.Request) {
+	var req PlayerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("Invalid request body", "error", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	player, ok := h.player(r.Context(), req.Target)
+	if !ok {
+		h.logger.Warn("Target is not a player", "target", req.Target)
+		http.Error(w, "Target is not a player", http.StatusBadRequest)
+		return
+	}
+	if !h.allowed(r.Context(), auth.SendTo(player.Name())) {
+		h.logger.Warn("Player control denied", "target", player.Name(), "user", userKey(r.Context()))
+		http.Error(w, "You don't have permission to control "+player.Name()+".", http.StatusForbidden)
+		return
+	}
+
+	value := max(0, min(req.Value, 100))
+	problem := ""
+	if err := player.Control(r.Context(), req.Action, value); err != nil {
+		h.logger.Warn("Player control failed", "target", player.Name(), "action", req.Action, "error", err)
+		problem = "Failed to control " + player.Name() + ", see the logs for details"
+	} else {
+		h.logger.Info("Player control", "target", player.Name(), "action", req.Action, "value", value)
+	}
+	h.renderPlayer(w, r, player, problem)
+}
+
+// player looks up a player target. An empty name selects the target the user
+// last sent to, as the picker does.
+func (h *Handlers) player(ctx context.Context, name string) (sink.Player, bool) {
+	if name == "" {
+		// A remembered target may since have been removed from the config
+		if last, ok := h.lastTargets.Get(userKey(ctx)); ok {
+			if _, known := h.target(ctx, last); known {
+				name = last
+			}
+		}
+	}
+	target, ok := h.target(ctx, name)
+	if !ok {
+		return nil, false
+	}
+	player, ok := target.(sink.Player)
+	return player, ok
+}
+
+// renderPlayer renders a player's state, showing problem in place of it if set
+func (h *Handlers) renderPlayer(w http.ResponseWriter, r *http.Request, player sink.Player, problem string) {
+	state, err := player.State(r.Context())
+	if err != nil && problem == "" {
+		h.logger.Debug("Failed to read player state", "target", player.Name(), "error", err)
+		problem = "Could not reach " + player.Name()
+	}
+	templates.PlayerStatus(player.Name(), state, problem, h.basePath).Render(r.Context(), w)
+}
+
+// UseTokens enables the API token pages, managing the tokens in the store
+func (h *Handlers) UseTokens(tokens *auth.TokenStore) {
+	h.tokens = tokens
+}
+
+// TokensHandler handles GET /tokens and lists the user's API tokens
+func (h *Handlers) TokensHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	templates.Tokens(h.tokens.List(user.Subject), h.basePath).Render(r.Context(), w)
+}
+
+// CreateTokenHandler handles POST /tokens, creating a token from the form and
+// returning the token list for HTMX with the new token shown once
+func (h *Handlers) CreateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		h.logger.Warn("Invalid token form", "error", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Expiry is offered in days, with an empty value for tokens that never expire
+	var expiresAt time.Time
+	if days := r.FormValue("expires"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid expiry", http.StatusBadRequest)
+			return
+		}
+		expiresAt = time.Now().AddDate(0, 0, n)
+	}
+
+	plain, token, err := h.tokens.Create(user, r.FormValue("name"), r.Form["scope"], expiresAt)
+	if err != nil {
+		h.logger.Warn("Failed to create API token", "user", user.PreferredUsername, "error", err)
+		templates.TokenList(h.tokens.List(user.Subject), "", err.Error(), h.basePath).Render(r.Context(), w)
+		return
+	}
+	h.logger.Info("API token created", "token", token.Name, "user", token.OwnerName, "scopes", token.Scopes, "expires_at", token.ExpiresAt)
+	templates.TokenList(h.tokens.List(user.Subject), plain, "", h.basePath).Render(r.Context(), w)
+}
+
+// RevokeTokenHandler handles DELETE /tokens/{tokenID} and returns the remaining tokens
+func (h *Handlers) RevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	problem := ""
+	token, found, err := h.tokens.Revoke(user.Subject, chi.URLParam(r, "tokenID"))
+	switch {
+	case err != nil:
+		h.logger.Error("Failed to revoke API token", "token", token.Name, "error", err)
+		problem = "Failed to revoke the token: " + err.Error()
+	case !found:
+		problem = "Token not found"
+	default:
+		h.logger.Info("API token revoked", "token", token.Name, "user", token.OwnerName)
+	}
+	templates.TokenList(h.tokens.List(user.Subject), "", problem, h.basePath).Render(r.Context(), w)
+}
+
+// UseSessions enables the admin sessions page, listing the sessions in the store
+func (h *Handlers) UseSessions(sessions *auth.SessionStore) {
+	h.sessions = sessions
+}
+
+// SessionsHandler handles GET /admin/sessions and lists everyone signed in
+func (h *Handlers) SessionsHandler(w http.ResponseWriter, r *http.Request) {
+	templates.Sessions(h.sessions.List(), auth.GetSessionFromContext(r.Context()), h.basePath).Render(r.Context(), w)
+}
+
+// RevokeSessionHandler handles DELETE /admin/sessions/{handle}, signing the
+// session out, and returns the remaining sessions
+func (h *Handlers) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	admin, _ := auth.GetUserFromContext(r.Context())
+	problem := ""
+	session, found := h.sessions.Revoke(chi.URLParam(r, "handle"))
+	if found {
+		h.logger.Info("Session killed", "by", admin.PreferredUsername, "user", session.Username, "session", session.Handle[:12])
+	} else {
+		problem = "Session not found; it may have ended already"
+	}
+	templates.SessionList(h.sessions.List(), auth.GetSessionFromContext(r.Context()), problem, h.basePath).Render(r.Context(), w)
 }
@@ -4,10 +4,15 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/git-saj/go-media-control/internal/config"
@@ -15,7 +20,11 @@ import (
 	"golang.org/x/oauth2"
 )
 
-// AuthService handles OIDC authentication with Authentik
+// refreshMargin is how long before the access token expires that a session
+// is renewed, so requests never go out with a token about to lapse
+const refreshMargin = time.Minute
+
+// AuthService handles OIDC authentication with any OpenID Connect provider
 type AuthService struct {
 	config       *config.Config
 	logger       *slog.Logger
@@ -23,14 +32,22 @@ type AuthService struct {
 	oauth2Config oauth2.Config
 	store        *sessions.CookieStore
 	basePath     string
+	endSession   string // Provider's RP-initiated logout endpoint; empty if it has none
+	policy       *Policy
+	tokens       *TokenStore
+	sessions     *SessionStore
+	refreshing   handleLocks // Serialises a session's renewals, so its refresh token is never spent twice
 }
 
 // UserInfo contains basic user information from OIDC
 type UserInfo struct {
-	Subject           string `json:"sub"`
-	Name              string `json:"name"`
-	PreferredUsername string `json:"preferred_username"`
-	Email             string `json:"email"`
+	Subject           string    `json:"sub"`
+	Name              string    `json:"name"`
+	PreferredUsername string    `json:"preferred_username"`
+	Email             string    `json:"email"`
+	ProviderSession   string    `json:"sid"` // Provider's session ID, named by back-channel logouts
+	Groups            []string  `json:"-"`   // Read from the configured groups claim
+	Token             *APIToken `json:"-"`   // Set when the request carries a personal access token
 }
 
 // NewAuthService creates a new authentication service
@@ -38,15 +55,22 @@ func NewAuthService(cfg *config.Config, logger *slog.Logger) (*AuthService, erro
 	ctx := context.Background()
 
 	// Discover OIDC provider configuration
-	baseURL := strings.TrimSuffix(cfg.AuthentikURL, "/")
-	providerURL := fmt.Sprintf("%s/application/o/go-media-control/", baseURL)
+	providerURL := cfg.OIDCIssuerURL
 	logger.Info("Initializing OIDC provider", "provider_url", providerURL)
 	provider, err := oidc.NewProvider(ctx, providerURL)
 	if err != nil {
 		logger.Error("Failed to initialize OIDC provider", "provider_url", providerURL, "error", err)
 		return nil, fmt.Errorf("failed to get OIDC provider at %s: %w", providerURL, err)
 	}
-	logger.Info("Successfully initialized OIDC provider")
+
+	// Global logout is only offered by providers advertising an end-session endpoint
+	var metadata struct {
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	if err := provider.Claims(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse OIDC provider metadata: %w", err)
+	}
+	logger.Info("Successfully initialized OIDC provider", "end_session_endpoint", metadata.EndSessionEndpoint)
 
 	// Configure OAuth2
 	oauth2Config := oauth2.Config{
@@ -54,7 +78,7 @@ func NewAuthService(cfg *config.Config, logger *slog.Logger) (*AuthService, erro
 		ClientSecret: cfg.ClientSecret,
 		RedirectURL:  cfg.RedirectURL,
 		Endpoint:     provider.Endpoint(),
-		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		Scopes:       cfg.OIDCScopes,
 	}
 
 	// Create secure cookie store
@@ -64,13 +88,22 @@ func NewAuthService(cfg *config.Config, logger *slog.Logger) (*AuthService, erro
 
 	store.Options = &sessions.Options{
 		Path:     "/",
-		MaxAge:   24 * 60 * 60, // 24 hours
+		MaxAge:   int(cfg.SessionLifetime.Seconds()),
 		HttpOnly: true,
 		Secure:   isProduction,         // Only secure cookies in production (HTTPS)
 		SameSite: http.SameSiteLaxMode, // Changed from Strict to Lax for OAuth callbacks
 		Domain:   "",                   // Allow cookies across subdomains if needed
 	}
 
+	tokens, err := NewTokenStore(cfg.APITokenFile, logger)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := NewSessionStore(cfg.SessionFile, cfg.SessionIdleTimeout, cfg.SessionLifetime, logger)
+	if err != nil {
+		return nil, err
+	}
+
 	return &AuthService{
 		config:       cfg,
 		logger:       logger,
@@ -78,6 +111,10 @@ func NewAuthService(cfg *config.Config, logger *slog.Logger) (*AuthService, erro
 		oauth2Config: oauth2Config,
 		store:        store,
 		basePath:     cfg.BasePath,
+		endSession:   metadata.EndSessionEndpoint,
+		policy:       NewPolicy(cfg),
+		tokens:       tokens,
+		sessions:     sessions,
 	}, nil
 }
 
@@ -103,95 +140,231 @@ func (a *AuthService) GetAuthURL() (string, string, error) {
 }
 
 // HandleCallback processes the OAuth2 callback and returns user information
-func (a *AuthService) HandleCallback(ctx context.Context, code, state string) (*UserInfo, string, error) {
+// along with the tokens the session keeps
+func (a *AuthService) HandleCallback(ctx context.Context, code, state string) (*UserInfo, sessionTokens, error) {
 	// Exchange code for tokens
 	token, err := a.oauth2Config.Exchange(ctx, code)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to exchange code: %w", err)
+		return nil, sessionTokens{}, fmt.Errorf("failed to exchange code: %w", err)
 	}
 
 	// Extract ID token
 	rawIDToken, ok := token.Extra("id_token").(string)
 	if !ok {
-		return nil, "", fmt.Errorf("no id_token in token response")
+		return nil, sessionTokens{}, fmt.Errorf("no id_token in token response")
+	}
+	userInfo, idTokenExpiry, err := a.verifyIDToken(ctx, rawIDToken)
+	if err != nil {
+		return nil, sessionTokens{}, err
 	}
 
-	// Verify ID token
+	if token.RefreshToken == "" && slices.Contains(a.config.OIDCScopes, "offline_access") {
+		// Some providers, such as Google, need extra parameters or refuse offline_access
+		a.logger.Warn("Provider issued no refresh token despite offline_access, session will not be renewed", "user", userInfo.PreferredUsername)
+	}
+
+	return userInfo, a.sessionTokens(token, rawIDToken, idTokenExpiry), nil
+}
+
+// verifyIDToken checks an ID token and reads the user from its claims,
+// returning when the token expires
+func (a *AuthService) verifyIDToken(ctx context.Context, rawIDToken string) (*UserInfo, time.Time, error) {
 	verifier := a.provider.Verifier(&oidc.Config{
 		ClientID: a.config.ClientID,
 	})
 
 	idToken, err := verifier.Verify(ctx, rawIDToken)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to verify ID token: %w", err)
+		return nil, time.Time{}, fmt.Errorf("failed to verify ID token: %w", err)
 	}
 
 	// Extract user info from ID token
 	var userInfo UserInfo
 	if err := idToken.Claims(&userInfo); err != nil {
-		return nil, "", fmt.Errorf("failed to parse user info: %w", err)
+		return nil, time.Time{}, fmt.Errorf("failed to parse user info: %w", err)
+	}
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to parse claims: %w", err)
 	}
+	userInfo.Groups = groupsFromClaims(claims, a.config.GroupsClaim)
 
-	return &userInfo, rawIDToken, nil
+	return &userInfo, idToken.Expiry, nil
 }
 
-// CreateSession creates a secure session for the authenticated user
-func (a *AuthService) CreateSession(w http.ResponseWriter, r *http.Request, userInfo *UserInfo, idToken string) error {
-	session, err := a.store.Get(r, "go-media-control-session")
+// sessionTokens picks the tokens a session keeps from a token response. The
+// access token's expiry drives renewal; providers that leave it out fall back
+// to the ID token's.
+func (a *AuthService) sessionTokens(token *oauth2.Token, rawIDToken string, idTokenExpiry time.Time) sessionTokens {
+	expiry := token.Expiry
+	if expiry.IsZero() {
+		expiry = idTokenExpiry
+	}
+	return sessionTokens{IDToken: rawIDToken, RefreshToken: token.RefreshToken, Expiry: expiry}
+}
+
+// CreateSession starts a server-side session for the authenticated user and
+// stores its ID in the session cookie
+func (a *AuthService) CreateSession(w http.ResponseWriter, r *http.Request, userInfo *UserInfo, tokens sessionTokens) error {
+	cookie, err := a.store.Get(r, "go-media-control-session")
 	if err != nil {
 		return fmt.Errorf("failed to get session: %w", err)
 	}
 
-	session.Values["user_id"] = userInfo.Subject
-	session.Values["username"] = userInfo.PreferredUsername
-	session.Values["name"] = userInfo.Name
-	session.Values["email"] = userInfo.Email
-	session.Values["id_token"] = idToken
-	session.Values["authenticated"] = true
+	id, session, err := a.sessions.Create(userInfo, tokens, r.RemoteAddr, r.UserAgent())
+	if err != nil {
+		return err
+	}
+	a.logger.Debug("Session created", "session", session.Handle[:12], "sid", session.SID, "renews", session.Renews())
 
-	return session.Save(r, w)
+	// The OAuth state is spent; the cookie now only identifies the session
+	delete(cookie.Values, "oauth_state")
+	delete(cookie.Values, "oauth_timestamp")
+	cookie.Values["session_id"] = id
+
+	return cookie.Save(r, w)
 }
 
 // ValidateSession validates a session and returns user info
 func (a *AuthService) ValidateSession(r *http.Request) (*UserInfo, error) {
-	session, err := a.store.Get(r, "go-media-control-session")
+	session, err := a.session(r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get session: %w", err)
+		return nil, err
 	}
+	return session.User(), nil
+}
 
-	authenticated, ok := session.Values["authenticated"].(bool)
-	if !ok || !authenticated {
-		return nil, fmt.Errorf("not authenticated")
+// session returns the server-side session named by the request's cookie
+func (a *AuthService) session(r *http.Request) (Session, error) {
+	cookie, err := a.store.Get(r, "go-media-control-session")
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to get session: %w", err)
 	}
 
-	userID, ok := session.Values["user_id"].(string)
+	id, ok := cookie.Values["session_id"].(string)
+	if !ok {
+		return Session{}, fmt.Errorf("not authenticated")
+	}
+	session, ok := a.sessions.Get(id)
 	if !ok {
-		return nil, fmt.Errorf("no user ID in session")
+		// Expired, logged out elsewhere or revoked by an admin
+		return Session{}, fmt.Errorf("session ended")
 	}
+	return session, nil
+}
 
-	username, _ := session.Values["username"].(string)
-	name, _ := session.Values["name"].(string)
-	email, _ := session.Values["email"].(string)
+// renew refreshes the session's tokens with its refresh token once the access
+// token is about to expire, picking up changes to the user's name and groups.
+// Sessions without a refresh token simply run until their idle or absolute
+// limit. It returns an error, having ended the session, when the provider
+// rejects the refresh token; if the provider cannot be reached the session
+// carries on and renewal is retried on the next request.
+func (a *AuthService) renew(ctx context.Context, session Session) (Session, error) {
+	if !session.Renews() || time.Until(session.TokenExpiry) > refreshMargin {
+		return session, nil
+	}
 
-	return &UserInfo{
-		Subject:           userID,
-		PreferredUsername: username,
-		Name:              name,
-		Email:             email,
-	}, nil
+	unlock := a.refreshing.lock(session.Handle)
+	defer unlock()
+	// A concurrent request may have renewed the session while this one waited
+	current, ok := a.sessions.Lookup(session.Handle)
+	if !ok {
+		return Session{}, fmt.Errorf("session ended")
+	}
+	if time.Until(current.TokenExpiry) > refreshMargin {
+		return current, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	token, err := a.oauth2Config.TokenSource(ctx, &oauth2.Token{RefreshToken: current.RefreshToken}).Token()
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.Response != nil && retrieveErr.Response.StatusCode < http.StatusInternalServerError {
+			a.sessions.Revoke(current.Handle)
+			a.logger.Info("Session ended, refresh rejected by provider", "user", current.Username, "session", current.Handle[:12], "error", err)
+			return Session{}, fmt.Errorf("refresh rejected: %w", err)
+		}
+		a.logger.Warn("Failed to renew session, will retry", "user", current.Username, "session", current.Handle[:12], "error", err)
+		return current, nil
+	}
+
+	// Refresh responses may carry a new ID token with updated claims
+	var user *UserInfo
+	rawIDToken, _ := token.Extra("id_token").(string)
+	var idTokenExpiry time.Time
+	if rawIDToken != "" {
+		if user, idTokenExpiry, err = a.verifyIDToken(ctx, rawIDToken); err != nil {
+			a.logger.Warn("Ignoring ID token from refresh", "user", current.Username, "error", err)
+			user, rawIDToken = nil, ""
+		} else if user.Subject != current.Subject {
+			a.sessions.Revoke(current.Handle)
+			a.logger.Warn("Session ended, refresh returned another subject", "user", current.Username, "session", current.Handle[:12])
+			return Session{}, fmt.Errorf("refresh returned another subject")
+		}
+	}
+	renewed, ok := a.sessions.Renewed(current.Handle, user, a.sessionTokens(token, rawIDToken, idTokenExpiry))
+	if !ok {
+		return Session{}, fmt.Errorf("session ended")
+	}
+	if user != nil {
+		a.tokens.UpdateGroups(user.Subject, user.Groups)
+	}
+	a.logger.Info("Session renewed", "user", renewed.Username, "session", renewed.Handle[:12], "expires", renewed.TokenExpiry)
+	return renewed, nil
+}
+
+// handleLocks holds a mutex per session handle while it is in use, so renewals
+// of different sessions do not wait on each other
+type handleLocks struct {
+	mu    sync.Mutex
+	locks map[string]*handleLock
+}
+
+type handleLock struct {
+	sync.Mutex
+	users int // Callers holding or waiting for the lock
+}
+
+// lock acquires the handle's mutex and returns the function releasing it
+func (l *handleLocks) lock(handle string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*handleLock)
+	}
+	hl, ok := l.locks[handle]
+	if !ok {
+		hl = &handleLock{}
+		l.locks[handle] = hl
+	}
+	hl.users++
+	l.mu.Unlock()
+
+	hl.Lock()
+	return func() {
+		hl.Unlock()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if hl.users--; hl.users == 0 {
+			delete(l.locks, handle)
+		}
+	}
 }
 
-// ClearSession removes the session
+// ClearSession ends the server-side session and removes the cookie
 func (a *AuthService) ClearSession(w http.ResponseWriter, r *http.Request) error {
-	session, err := a.store.Get(r, "go-media-control-session")
+	cookie, err := a.store.Get(r, "go-media-control-session")
 	if err != nil {
 		return fmt.Errorf("failed to get session: %w", err)
 	}
 
-	session.Values["authenticated"] = false
-	session.Options.MaxAge = -1 // Delete immediately
+	if id, ok := cookie.Values["session_id"].(string); ok {
+		a.sessions.Delete(id)
+	}
+	delete(cookie.Values, "session_id")
+	cookie.Options.MaxAge = -1 // Delete immediately
 
-	return session.Save(r, w)
+	return cookie.Save(r, w)
 }
 
 // RequireAuth is middleware that ensures the user is authenticated
@@ -205,7 +378,27 @@ func (a *AuthService) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		userInfo, err := a.ValidateSession(r)
+		// Scripts reach the API with a personal access token instead of a session
+		if strings.HasPrefix(r.URL.Path, strings.TrimSuffix(a.basePath, "/")+"/api/") {
+			if plain, ok := bearerToken(r); ok {
+				token, ok := a.tokens.Authenticate(plain)
+				if !ok {
+					a.logger.Warn("Rejected API token", "method", r.Method, "path", r.URL.Path)
+					http.Error(w, "Invalid or expired API token", http.StatusUnauthorized)
+					return
+				}
+				a.logger.Info("API token used", "token", token.Name, "user", token.OwnerName, "method", r.Method, "path", r.URL.Path)
+				user := &UserInfo{Subject: token.Owner, PreferredUsername: token.OwnerName, Groups: token.Groups, Token: &token}
+				ctx := context.WithValue(r.Context(), "user", user)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+		}
+
+		session, err := a.session(r)
+		if err == nil {
+			session, err = a.renew(r.Context(), session)
+		}
 		if err != nil {
 			a.logger.Debug("Authentication required", "error", err, "path", r.URL.Path)
 			loginURL := strings.TrimSuffix(a.basePath, "/") + "/auth/login"
@@ -213,14 +406,56 @@ func (a *AuthService) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		// Add user info to request context
-		ctx := context.WithValue(r.Context(), "user", userInfo)
+		// Add user info and the session's handle to request context
+		ctx := context.WithValue(r.Context(), "user", session.User())
+		ctx = context.WithValue(ctx, "session", session.Handle)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// GetSessionFromContext returns the handle of the request's session, which is
+// empty for requests made with an API token
+func GetSessionFromContext(ctx context.Context) string {
+	handle, _ := ctx.Value("session").(string)
+	return handle
+}
+
+// Sessions returns the store of signed-in sessions
+func (a *AuthService) Sessions() *SessionStore {
+	return a.sessions
+}
+
+// Tokens returns the store of personal access tokens
+func (a *AuthService) Tokens() *TokenStore {
+	return a.tokens
+}
+
 // GetUserFromContext extracts user info from request context
 func GetUserFromContext(ctx context.Context) (*UserInfo, bool) {
 	user, ok := ctx.Value("user").(*UserInfo)
 	return user, ok
 }
+
+// EndSessionURL returns the provider's RP-initiated logout URL, sending the
+// user back to the logged-out page afterwards, or false if the provider does
+// not support it
+func (a *AuthService) EndSessionURL(idToken string) (string, bool) {
+	if a.endSession == "" {
+		return "", false
+	}
+	logoutURL, err := url.Parse(a.endSession)
+	if err != nil {
+		a.logger.Error("Invalid end-session endpoint", "url", a.endSession, "error", err)
+		return "", false
+	}
+
+	// The logged-out page sits next to the callback, under the same base path
+	query := logoutURL.Query()
+	query.Set("post_logout_redirect_uri", strings.TrimSuffix(a.config.RedirectURL, "callback")+"logged-out")
+	query.Set("client_id", a.config.ClientID)
+	if idToken != "" {
+		query.Set("id_token_hint", idToken)
+	}
+	logoutURL.RawQuery = query.Encode()
+	return logoutURL.String(), true
+}
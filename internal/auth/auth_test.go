@@ -0,0 +1,300 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// mockIssuer is a minimal OpenID Connect provider serving discovery, JWKS and
+// the token endpoint, signing ID tokens with its own RSA key
+type mockIssuer struct {
+	*httptest.Server
+	key        *rsa.PrivateKey
+	endSession bool // Advertise an end_session_endpoint
+	mu         sync.Mutex
+	tokenForms []url.Values
+	claims     map[string]any // Extra claims for issued ID tokens
+}
+
+func newMockIssuer(t *testing.T, endSession bool) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{key: key, endSession: endSession}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/keys", m.jwks)
+	mux.HandleFunc("/token", m.token)
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	metadata := map[string]any{
+		"issuer":                                m.URL,
+		"authorization_endpoint":                m.URL + "/authorize",
+		"token_endpoint":                        m.URL + "/token",
+		"jwks_uri":                              m.URL + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	}
+	if m.endSession {
+		metadata["end_session_endpoint"] = m.URL + "/logout?tenant=home"
+	}
+	json.NewEncoder(w).Encode(metadata)
+}
+
+func (m *mockIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"alg": "RS256",
+		"use": "sig",
+		"kid": "test",
+		"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+	}}})
+}
+
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	m.mu.Lock()
+	m.tokenForms = append(m.tokenForms, r.PostForm)
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token":  "access",
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+		"refresh_token": "refresh",
+		"id_token":      m.idToken(),
+	})
+}
+
+// idToken signs an ID token for the test user
+func (m *mockIssuer) idToken() string {
+	now := time.Now()
+	claims := map[string]any{
+		"iss":                m.URL,
+		"sub":                "user-1",
+		"aud":                "gmc",
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+		"preferred_username": "alice",
+		"name":               "Alice",
+		"email":              "alice@example.org",
+		"sid":                "provider-session",
+	}
+	m.mu.Lock()
+	for name, value := range m.claims {
+		claims[name] = value
+	}
+	m.mu.Unlock()
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signing))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signing + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func testConfig(issuer string) *config.Config {
+	return &config.Config{
+		OIDCIssuerURL:      issuer,
+		OIDCScopes:         []string{"openid", "profile", "groups"},
+		ClientID:           "gmc",
+		ClientSecret:       "secret",
+		RedirectURL:        "https://media.example/app/auth/callback",
+		SessionSecret:      "0123456789abcdef0123456789abcdef",
+		BasePath:           "/app/",
+		GroupsClaim:        "groups",
+		SessionIdleTimeout: time.Hour,
+		SessionLifetime:    24 * time.Hour,
+	}
+}
+
+func newTestService(t *testing.T, cfg *config.Config) *AuthService {
+	t.Helper()
+	a, err := NewAuthService(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewAuthService: %v", err)
+	}
+	return a
+}
+
+func TestAuthURLUsesConfiguredIssuerAndScopes(t *testing.T) {
+	issuer := newMockIssuer(t, false)
+	a := newTestService(t, testConfig(issuer.URL))
+
+	authURL, state, err := a.GetAuthURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != issuer.URL+"/authorize" {
+		t.Errorf("authorization endpoint = %s, want the discovered %s/authorize", got, issuer.URL)
+	}
+	query := u.Query()
+	want := map[string]string{
+		"client_id":     "gmc",
+		"redirect_uri":  "https://media.example/app/auth/callback",
+		"response_type": "code",
+		"scope":         "openid profile groups",
+		"state":         state,
+	}
+	for name, value := range want {
+		if query.Get(name) != value {
+			t.Errorf("%s = %q, want %q", name, query.Get(name), value)
+		}
+	}
+}
+
+func TestNewAuthServiceRejectsUnreachableIssuer(t *testing.T) {
+	issuer := newMockIssuer(t, false)
+	issuer.Close()
+	if _, err := NewAuthService(testConfig(issuer.URL), slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Fatal("expected discovery against a closed issuer to fail")
+	}
+}
+
+func TestHandleCallback(t *testing.T) {
+	issuer := newMockIssuer(t, false)
+	issuer.claims = map[string]any{"groups": []string{"family", "admins"}}
+	a := newTestService(t, testConfig(issuer.URL))
+
+	user, tokens, err := a.HandleCallback(context.Background(), "the-code", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Subject != "user-1" || user.PreferredUsername != "alice" || user.Email != "alice@example.org" || user.ProviderSession != "provider-session" {
+		t.Errorf("user = %+v", user)
+	}
+	if !slices.Equal(user.Groups, []string{"family", "admins"}) {
+		t.Errorf("groups = %v", user.Groups)
+	}
+	if tokens.IDToken == "" || tokens.RefreshToken != "refresh" || time.Until(tokens.Expiry) < 50*time.Minute {
+		t.Errorf("tokens = %+v", tokens)
+	}
+
+	form := issuer.tokenForms[0]
+	if form.Get("grant_type") != "authorization_code" || form.Get("code") != "the-code" || form.Get("redirect_uri") != "https://media.example/app/auth/callback" {
+		t.Errorf("token request = %v", form)
+	}
+}
+
+func TestHandleCallbackRejectsForeignAudience(t *testing.T) {
+	issuer := newMockIssuer(t, false)
+	issuer.claims = map[string]any{"aud": "another-client"}
+	a := newTestService(t, testConfig(issuer.URL))
+
+	if _, _, err := a.HandleCallback(context.Background(), "code", "state"); err == nil || !strings.Contains(err.Error(), "verify ID token") {
+		t.Fatalf("HandleCallback error = %v, want an ID token verification failure", err)
+	}
+}
+
+func TestEndSessionURL(t *testing.T) {
+	issuer := newMockIssuer(t, true)
+	a := newTestService(t, testConfig(issuer.URL))
+
+	logoutURL, ok := a.EndSessionURL("id-token")
+	if !ok {
+		t.Fatal("EndSessionURL reported no end_session_endpoint")
+	}
+	u, err := url.Parse(logoutURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != issuer.URL+"/logout" {
+		t.Errorf("end-session endpoint = %s, want %s/logout", got, issuer.URL)
+	}
+	query := u.Query()
+	want := map[string]string{
+		"tenant":                   "home", // Query of the discovered endpoint is kept
+		"client_id":                "gmc",
+		"id_token_hint":            "id-token",
+		"post_logout_redirect_uri": "https://media.example/app/auth/logged-out",
+	}
+	for name, value := range want {
+		if query.Get(name) != value {
+			t.Errorf("%s = %q, want %q", name, query.Get(name), value)
+		}
+	}
+
+	logoutURL, _ = a.EndSessionURL("")
+	if u, _ := url.Parse(logoutURL); u.Query().Has("id_token_hint") {
+		t.Errorf("empty ID token sent as a hint: %s", logoutURL)
+	}
+}
+
+func TestEndSessionURLWithoutEndpoint(t *testing.T) {
+	issuer := newMockIssuer(t, false)
+	a := newTestService(t, testConfig(issuer.URL))
+
+	if logoutURL, ok := a.EndSessionURL("id-token"); ok || logoutURL != "" {
+		t.Errorf("EndSessionURL = %q, %v; want the local-only fallback", logoutURL, ok)
+	}
+}
+
+func TestHandleLocks(t *testing.T) {
+	var l handleLocks
+	unlockA := l.lock("a")
+
+	// Another session's renewal does not wait for this one
+	done := make(chan struct{})
+	go func() {
+		l.lock("b")()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("lock on another handle blocked")
+	}
+
+	// The same session's renewal waits
+	released := make(chan struct{})
+	go func() {
+		l.lock("a")()
+		close(released)
+	}()
+	select {
+	case <-released:
+		t.Fatal("second lock on the same handle did not wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlockA()
+	<-released
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.locks) != 0 {
+		t.Errorf("%d locks kept after release, want none", len(l.locks))
+	}
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// Permission is an action a user can be granted through their groups
+type Permission string
+
+const (
+	PermView    Permission = "view"    // Browse channels, the guide and player state
+	PermSend    Permission = "send"    // Send to and control every target
+	PermRefresh Permission = "refresh" // Flush and refetch the caches
+	PermAdmin   Permission = "admin"   // Everything, including administration pages
+)
+
+// SendTo is the permission to send to and control a single target
+func SendTo(target string) Permission {
+	return Permission("send:" + target)
+}
+
+// Policy decides what a user may do from the groups they are in
+type Policy struct {
+	grants map[Permission][]string // Groups granted each permission
+}
+
+// NewPolicy creates the policy configured through the AUTHZ_* settings
+func NewPolicy(cfg *config.Config) *Policy {
+	grants := make(map[Permission][]string, len(cfg.Permissions))
+	for permission, groups := range cfg.Permissions {
+		grants[Permission(permission)] = groups
+	}
+	return &Policy{grants: grants}
+}
+
+// Allows reports whether the user holds the permission. Without any grants
+// configured everyone does. Admins hold every permission, "send" covers every
+// target, and anyone who may send or refresh may also view; when no groups are
+// granted "view", every signed-in user may view. PermSend is also held by users
+// who may send to just one target, so a route can require it before its
+// handler checks the chosen target with SendTo. Requests made with a personal
+// access token are further limited to the token's scopes.
+func (p *Policy) Allows(user *UserInfo, perm Permission) bool {
+	if user != nil && user.Token != nil && !user.Token.permits(perm) {
+		return false
+	}
+	if len(p.grants) == 0 {
+		return true
+	}
+	if user == nil {
+		return false
+	}
+	if p.granted(user, PermAdmin) || p.granted(user, perm) {
+		return true
+	}
+
+	switch {
+	case perm == PermView:
+		if _, ok := p.grants[PermView]; !ok {
+			return true
+		}
+		return p.Allows(user, PermSend) || p.granted(user, PermRefresh)
+	case perm == PermSend:
+		for permission := range p.grants {
+			if strings.HasPrefix(string(permission), "send:") && p.granted(user, permission) {
+				return true
+			}
+		}
+		return false
+	case strings.HasPrefix(string(perm), "send:"):
+		return p.granted(user, PermSend)
+	}
+	return false
+}
+
+// granted reports whether the user is in one of the groups granted the permission itself
+func (p *Policy) granted(user *UserInfo, perm Permission) bool {
+	for _, group := range p.grants[perm] {
+		if slices.Contains(user.Groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission is middleware, used after RequireAuth, that answers 403
+// unless the signed-in user holds the permission
+func (a *AuthService) RequirePermission(perm Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, _ := GetUserFromContext(r.Context())
+			if !a.policy.Allows(user, perm) {
+				a.forbidden(w, r, user, perm)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forbidden explains a missing permission: as plain text to HTMX requests,
+// which show it in place, and to API token clients, and as a page to browsers
+func (a *AuthService) forbidden(w http.ResponseWriter, r *http.Request, user *UserInfo, perm Permission) {
+	var username string
+	var groups []string
+	if user != nil {
+		username, groups = user.PreferredUsername, user.Groups
+		if username == "" {
+			username = user.Subject
+		}
+	}
+	a.logger.Warn("Permission denied", "user", username, "groups", groups, "permission", perm, "path", r.URL.Path)
+
+	message := fmt.Sprintf("You don't have permission to %s.", perm.describe())
+	if user != nil && user.Token != nil {
+		message = fmt.Sprintf("API token %q does not allow you to %s.", user.Token.Name, perm.describe())
+	}
+	if r.Header.Get("HX-Request") == "true" || (user != nil && user.Token != nil) {
+		http.Error(w, message, http.StatusForbidden)
+		return
+	}
+
+	// Listing the user's groups helps tell a missing grant from a missing claim
+	membership := "Your account is not in any groups. Check that your identity provider sends the \"" + a.config.GroupsClaim + "\" claim."
+	if len(groups) > 0 {
+		membership = "Your groups: " + strings.Join(groups, ", ") + "."
+	}
+	base := strings.TrimSuffix(a.basePath, "/")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head>
+    <title>Access Denied - Go Media Control</title>
+    <style>
+        body { font-family: Arial, sans-serif; text-align: center; margin-top: 50px; }
+        .container { max-width: 480px; margin: 0 auto; padding: 20px; }
+        .message { color: #666; margin-bottom: 20px; }
+        .btn { display: inline-block; padding: 10px 20px; background: #007bff; color: white; text-decoration: none; border-radius: 4px; margin: 0 4px; }
+        .btn:hover { background: #0056b3; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>Access Denied</h1>
+        <p>%s</p>
+        <p class="message">Signed in as %s. %s Ask an administrator for access.</p>
+        <a href="%s/" class="btn">Home</a>
+        <a href="%s/auth/logout" class="btn">Switch Account</a>
+    </div>
+</body>
+</html>`, html.EscapeString(message), html.EscapeString(username), html.EscapeString(membership), base, base)
+}
+
+// describe names the permission for people, e.g. "send to living-room"
+func (p Permission) describe() string {
+	switch p {
+	case PermView:
+		return "view this page"
+	case PermSend:
+		return "send streams"
+	case PermRefresh:
+		return "refresh the caches"
+	case PermAdmin:
+		return "administer Go Media Control"
+	}
+	if target, ok := strings.CutPrefix(string(p), "send:"); ok {
+		return "send to " + target
+	}
+	return string(p)
+}
+
+// groupsFromClaims reads the user's groups or roles from the ID token claims.
+// The claim may be a dotted path into nested objects, such as Keycloak's
+// realm_access.roles, and hold a list, a single name, or an object keyed by
+// role name as Zitadel sends.
+func groupsFromClaims(claims map[string]any, claim string) []string {
+	value, ok := claims[claim]
+	if !ok {
+		var current any = claims
+		for _, part := range strings.Split(claim, ".") {
+			object, isObject := current.(map[string]any)
+			if !isObject {
+				return nil
+			}
+			current = object[part]
+		}
+		value = current
+	}
+
+	var groups []string
+	switch v := value.(type) {
+	case string:
+		groups = append(groups, v)
+	case []any:
+		for _, item := range v {
+			if group, ok := item.(string); ok {
+				groups = append(groups, group)
+			}
+		}
+	case map[string]any:
+		for group := range v {
+			groups = append(groups, group)
+		}
+		slices.Sort(groups)
+	}
+	return groups
+}
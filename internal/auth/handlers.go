@@ -2,10 +2,10 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
@@ -57,7 +57,7 @@ func (h *AuthHandlers) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	// Clear any existing auth data
 	session.Values["oauth_state"] = state
 	session.Values["oauth_timestamp"] = time.Now().Unix()
-	session.Values["authenticated"] = false
+	delete(session.Values, "session_id")
 
 	if err := session.Save(r, w); err != nil {
 		h.logger.Error("Failed to save session state", "error", err)
@@ -71,7 +71,7 @@ func (h *AuthHandlers) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
 }
 
-// CallbackHandler handles the OAuth2 callback from Authentik
+// CallbackHandler handles the OAuth2 callback from the OIDC provider
 func (h *AuthHandlers) CallbackHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
@@ -126,15 +126,18 @@ func (h *AuthHandlers) CallbackHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Exchange code for user info
-	userInfo, idToken, err := h.authService.HandleCallback(ctx, code, state)
+	userInfo, tokens, err := h.authService.HandleCallback(ctx, code, state)
 	if err != nil {
 		h.logger.Error("Failed to handle OAuth callback", "error", err)
 		http.Error(w, "Authentication failed", http.StatusInternalServerError)
 		return
 	}
 
+	// Tokens act with the groups the user had at their last login
+	h.authService.tokens.UpdateGroups(userInfo.Subject, userInfo.Groups)
+
 	// Create session for authenticated user
-	if err := h.authService.CreateSession(w, r, userInfo, idToken); err != nil {
+	if err := h.authService.CreateSession(w, r, userInfo, tokens); err != nil {
 		h.logger.Error("Failed to create user session", "error", err)
 		http.Error(w, "Session creation failed", http.StatusInternalServerError)
 		return
@@ -143,15 +146,23 @@ func (h *AuthHandlers) CallbackHandler(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("User successfully authenticated",
 		"user_id", userInfo.Subject,
 		"username", userInfo.PreferredUsername,
-		"name", userInfo.Name)
+		"name", userInfo.Name,
+		"groups", userInfo.Groups)
 
 	// Redirect to home page
 	homeURL := strings.TrimSuffix(h.authService.basePath, "/") + "/"
 	http.Redirect(w, r, homeURL, http.StatusTemporaryRedirect)
 }
 
-// LogoutHandler clears the user session and optionally redirects to Authentik logout
+// LogoutHandler clears the user session and, with ?global=true, also ends the
+// session at the OIDC provider when it advertises an end-session endpoint
 func (h *AuthHandlers) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	// Read the ID token for the provider's hint before the session is cleared
+	idToken := ""
+	if session, err := h.authService.session(r); err == nil {
+		idToken = session.IDToken
+	}
+
 	// Clear the session
 	if err := h.authService.ClearSession(w, r); err != nil {
 		h.logger.Error("Failed to clear session", "error", err)
@@ -161,33 +172,12 @@ func (h *AuthHandlers) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("User logged out")
 	h.logger.Info("Logout request", "url", r.URL.String(), "global", r.URL.Query().Get("global"))
 
-	// Check if we should redirect to Authentik logout
-	baseURL := strings.TrimSuffix(h.authService.config.AuthentikURL, "/")
-	logoutURL := fmt.Sprintf("%s/application/o/go-media-control/end-session/", baseURL)
-
-	// You can optionally redirect to Authentik's logout endpoint
-	// Check if we should redirect to Authentik logout
-	redirectToAuthentik := r.URL.Query().Get("global") == "true"
-
-	if redirectToAuthentik {
-		// Get stored ID token for hint
-		session, err := h.authService.store.Get(r, "go-media-control-session")
-		idToken := ""
-		if err == nil {
-			if id, ok := session.Values["id_token"].(string); ok {
-				idToken = id
-			}
+	if r.URL.Query().Get("global") == "true" {
+		if logoutURL, ok := h.authService.EndSessionURL(idToken); ok {
+			http.Redirect(w, r, logoutURL, http.StatusTemporaryRedirect)
+			return
 		}
-
-		// Add post logout redirect URL if needed
-		redirectBaseURL := h.authService.config.RedirectURL[:len(h.authService.config.RedirectURL)-len("/auth/callback")]
-		postLogoutURL := fmt.Sprintf("%s%sauth/logged-out", redirectBaseURL, h.authService.basePath)
-		logoutURL += fmt.Sprintf("?post_logout_redirect_uri=%s", postLogoutURL)
-		if idToken != "" {
-			logoutURL += fmt.Sprintf("&id_token_hint=%s", url.QueryEscape(idToken))
-		}
-		http.Redirect(w, r, logoutURL, http.StatusTemporaryRedirect)
-		return
+		h.logger.Warn("OIDC provider has no end-session endpoint, logging out locally only")
 	}
 
 	// Simple logout - just redirect to login page
@@ -232,13 +222,16 @@ func (h *AuthHandlers) UserInfoHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	groups, _ := json.Marshal(append([]string{}, userInfo.Groups...))
+
 	w.Header().Set("Content-Type", "application/json")
 	response := fmt.Sprintf(`{
 		"subject": "%s",
 		"username": "%s",
 		"name": "%s",
-		"email": "%s"
-	}`, userInfo.Subject, userInfo.PreferredUsername, userInfo.Name, userInfo.Email)
+		"email": "%s",
+		"groups": %s
+	}`, userInfo.Subject, userInfo.PreferredUsername, userInfo.Name, userInfo.Email, groups)
 
 	w.Write([]byte(response))
 }
@@ -272,8 +265,10 @@ func (h *AuthHandlers) BackChannelLogoutHandler(w http.ResponseWriter, r *http.R
 	}
 
 	var claims struct {
-		Sub string `json:"sub"`
-		Sid string `json:"sid"`
+		Sub    string                     `json:"sub"`
+		Sid    string                     `json:"sid"`
+		Nonce  *string                    `json:"nonce"`
+		Events map[string]json.RawMessage `json:"events"`
 	}
 
 	err = idToken.Claims(&claims)
@@ -283,8 +278,23 @@ func (h *AuthHandlers) BackChannelLogoutHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	h.logger.Info("Back-channel logout processed", "sub", claims.Sub, "sid", claims.Sid)
+	// A logout token names the logout event, and must not pass for an ID token
+	// or be answered by one, hence the nonce check
+	if _, ok := claims.Events["http://schemas.openid.net/event/backchannel-logout"]; !ok || claims.Nonce != nil {
+		h.logger.Error("Token is not a logout token", "sub", claims.Sub, "sid", claims.Sid)
+		http.Error(w, "Invalid logout token", http.StatusBadRequest)
+		return
+	}
+	if claims.Sub == "" && claims.Sid == "" {
+		h.logger.Error("Logout token names neither a subject nor a session")
+		http.Error(w, "Invalid logout token", http.StatusBadRequest)
+		return
+	}
+
+	ended := h.authService.sessions.RevokeProvider(claims.Sid, claims.Sub)
+	h.logger.Info("Back-channel logout processed", "sub", claims.Sub, "sid", claims.Sid, "sessions_ended", ended)
 
+	w.Header().Set("Cache-Control", "no-store")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Logout processed"))
 }
@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// lastSeenInterval limits how often a session's last activity is written to disk
+const lastSeenInterval = time.Minute
+
+// Session is a login held on the server. The cookie only carries its ID, so
+// removing the session here signs the browser out.
+type Session struct {
+	Handle       string    `json:"handle"` // Hex SHA-256 of the cookie's session ID, safe to show and log
+	SID          string    `json:"sid"`    // Provider session ID from the ID token, if sent
+	Subject      string    `json:"sub"`
+	Username     string    `json:"username"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	Groups       []string  `json:"groups"`
+	IDToken      string    `json:"id_token"`      // Hint for RP-initiated logout
+	RefreshToken string    `json:"refresh_token"` // Empty when the provider issued none
+	TokenExpiry  time.Time `json:"token_expiry"`  // When the access token expires and is renewed
+	RemoteAddr   string    `json:"remote_addr"`
+	UserAgent    string    `json:"user_agent"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastSeen     time.Time `json:"last_seen"`
+	ExpiresAt    time.Time `json:"expires_at"` // Absolute end, however active the session is
+}
+
+// sessionTokens are the provider's tokens a session keeps
+type sessionTokens struct {
+	IDToken      string
+	RefreshToken string
+	Expiry       time.Time
+}
+
+// Renews reports whether the session's tokens are renewed with a refresh
+// token; the provider has to issue one and say when the access token expires
+func (s *Session) Renews() bool {
+	return s.RefreshToken != "" && !s.TokenExpiry.IsZero()
+}
+
+// expired reports whether the session has reached its absolute end or been idle too long
+func (s *Session) expired(now time.Time, idleTimeout time.Duration) bool {
+	return !now.Before(s.ExpiresAt) || now.Sub(s.LastSeen) >= idleTimeout
+}
+
+// User returns the signed-in user of the session
+func (s *Session) User() *UserInfo {
+	return &UserInfo{
+		Subject:           s.Subject,
+		PreferredUsername: s.Username,
+		Name:              s.Name,
+		Email:             s.Email,
+		Groups:            s.Groups,
+		ProviderSession:   s.SID,
+	}
+}
+
+// SessionStore keeps the sessions in memory, saving them to a JSON file when
+// one is configured so logins survive a restart
+type SessionStore struct {
+	path        string        // Empty keeps sessions in memory only
+	idleTimeout time.Duration // Inactivity after which a session ends
+	lifetime    time.Duration // Time from sign-in after which a session ends
+	logger      *slog.Logger
+	mu          sync.Mutex
+	sessions    map[string]*Session // By handle
+}
+
+// NewSessionStore loads the sessions saved at path, dropping expired ones. An
+// empty path keeps sessions in memory, so everyone signs in again after a restart.
+func NewSessionStore(path string, idleTimeout, lifetime time.Duration, logger *slog.Logger) (*SessionStore, error) {
+	s := &SessionStore{path: path, idleTimeout: idleTimeout, lifetime: lifetime, logger: logger, sessions: make(map[string]*Session)}
+	if path == "" {
+		logger.Warn("SESSION_FILE and CACHE_DIR are unset, users will sign in again after a restart")
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions: %w", err)
+	}
+	var sessions []*Session
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to parse sessions in %s: %w", path, err)
+	}
+	now := time.Now()
+	for _, session := range sessions {
+		if !session.expired(now, idleTimeout) {
+			s.sessions[session.Handle] = session
+		}
+	}
+	logger.Info("Loaded sessions", "path", path, "count", len(s.sessions))
+	return s, nil
+}
+
+// Create starts a session for the user and returns the ID for the cookie
+func (s *SessionStore) Create(user *UserInfo, tokens sessionTokens, remoteAddr, userAgent string) (string, *Session, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	id := base64.RawURLEncoding.EncodeToString(b)
+	now := time.Now()
+	session := &Session{
+		Handle:       sessionHandle(id),
+		SID:          user.ProviderSession,
+		Subject:      user.Subject,
+		Username:     user.PreferredUsername,
+		Name:         user.Name,
+		Email:        user.Email,
+		Groups:       user.Groups,
+		IDToken:      tokens.IDToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenExpiry:  tokens.Expiry,
+		RemoteAddr:   remoteAddr,
+		UserAgent:    userAgent,
+		CreatedAt:    now,
+		LastSeen:     now,
+		ExpiresAt:    now.Add(s.lifetime),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+	s.sessions[session.Handle] = session
+	if err := s.save(); err != nil {
+		delete(s.sessions, session.Handle)
+		return "", nil, err
+	}
+	return id, session, nil
+}
+
+// Get returns a copy of the unexpired session with the cookie's ID and records the activity
+func (s *SessionStore) Get(id string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[sessionHandle(id)]
+	if !ok {
+		return Session{}, false
+	}
+	now := time.Now()
+	if session.expired(now, s.idleTimeout) {
+		delete(s.sessions, session.Handle)
+		s.saveOrWarn()
+		return Session{}, false
+	}
+	// Short idle timeouts need activity recorded more often to be honoured
+	if now.Sub(session.LastSeen) >= min(lastSeenInterval, s.idleTimeout/10) {
+		session.LastSeen = now
+		s.saveOrWarn()
+	}
+	return *session, true
+}
+
+// Lookup returns a copy of the session with the handle without recording activity
+func (s *SessionStore) Lookup(handle string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[handle]
+	if !ok || session.expired(time.Now(), s.idleTimeout) {
+		return Session{}, false
+	}
+	return *session, true
+}
+
+// Renewed stores the tokens and user details of a refresh, reporting false
+// if the session ended in the meantime
+func (s *SessionStore) Renewed(handle string, user *UserInfo, tokens sessionTokens) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[handle]
+	if !ok {
+		return Session{}, false
+	}
+	// Providers may leave out the ID token, or rotate the refresh token
+	if tokens.IDToken != "" {
+		session.IDToken = tokens.IDToken
+	}
+	if tokens.RefreshToken != "" {
+		session.RefreshToken = tokens.RefreshToken
+	}
+	session.TokenExpiry = tokens.Expiry
+	if user != nil {
+		session.Username, session.Name, session.Email, session.Groups = user.PreferredUsername, user.Name, user.Email, user.Groups
+	}
+	s.saveOrWarn()
+	return *session, true
+}
+
+// List returns the active sessions, most recently active first
+func (s *SessionStore) List() []Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	sessions := make([]Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, *session)
+	}
+	slices.SortFunc(sessions, func(a, b Session) int { return b.LastSeen.Compare(a.LastSeen) })
+	return sessions
+}
+
+// Delete ends the session with the cookie's ID
+func (s *SessionStore) Delete(id string) {
+	s.Revoke(sessionHandle(id))
+}
+
+// Revoke ends the session with the handle, returning it if it existed
+func (s *SessionStore) Revoke(handle string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[handle]
+	if !ok {
+		return Session{}, false
+	}
+	delete(s.sessions, handle)
+	s.saveOrWarn()
+	return *session, true
+}
+
+// RevokeProvider ends the sessions a back-channel logout names: those with
+// the provider session ID when one is given, otherwise every session of the
+// subject. It returns the number of sessions ended.
+func (s *SessionStore) RevokeProvider(sid, subject string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for handle, session := range s.sessions {
+		if sid != "" && session.SID != sid {
+			continue
+		}
+		if subject != "" && session.Subject != subject {
+			continue
+		}
+		delete(s.sessions, handle)
+		n++
+	}
+	if n > 0 {
+		s.saveOrWarn()
+	}
+	return n
+}
+
+// prune drops expired sessions; the caller holds s.mu
+func (s *SessionStore) prune(now time.Time) {
+	for handle, session := range s.sessions {
+		if session.expired(now, s.idleTimeout) {
+			delete(s.sessions, handle)
+		}
+	}
+}
+
+// save writes the sessions; the caller holds s.mu
+func (s *SessionStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	sessions := make([]*Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	if err := saveJSON(s.path, sessions); err != nil {
+		return fmt.Errorf("failed to save sessions: %w", err)
+	}
+	return nil
+}
+
+// saveOrWarn saves the sessions after a change that must not fail the request;
+// the caller holds s.mu
+func (s *SessionStore) saveOrWarn() {
+	if err := s.save(); err != nil {
+		s.logger.Warn("Failed to save sessions", "error", err)
+	}
+}
+
+// sessionHandle derives the public handle of a session ID, so the file and
+// the admin page never hold IDs that could be replayed as cookies
+func sessionHandle(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
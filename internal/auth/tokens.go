@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token scopes
+const (
+	ScopeRead = "read" // GET requests to /api/*, such as the media list and player state
+	ScopeSend = "send" // Sending streams and controlling players
+)
+
+// tokenPrefix marks personal access tokens so they are easy to recognise and
+// to spot in leaked text
+const tokenPrefix = "gmc_"
+
+// lastUsedInterval limits how often a token's last use is written to disk
+const lastUsedInterval = time.Minute
+
+// APIToken is a personal access token. Only a hash of the token is kept.
+type APIToken struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"` // Hex-encoded SHA-256 of the token
+	Scopes    []string  `json:"scopes"`
+	Owner     string    `json:"owner"`      // Subject of the user who created it
+	OwnerName string    `json:"owner_name"` // Username, for logs and embeds
+	Groups    []string  `json:"groups"`     // Owner's groups as of their last login
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"` // Zero for tokens that never expire
+	LastUsed  time.Time `json:"last_used"`
+}
+
+// Expired reports whether the token can no longer be used
+func (t *APIToken) Expired() bool {
+	return !t.ExpiresAt.IsZero() && time.Now().After(t.ExpiresAt)
+}
+
+// permits reports whether the token's scopes cover the permission. Tokens
+// never cover refreshing or administration.
+func (t *APIToken) permits(perm Permission) bool {
+	switch {
+	case perm == PermView:
+		return slices.Contains(t.Scopes, ScopeRead)
+	case perm == PermSend, strings.HasPrefix(string(perm), "send:"):
+		return slices.Contains(t.Scopes, ScopeSend)
+	}
+	return false
+}
+
+// TokenStore keeps the personal access tokens in a JSON file
+type TokenStore struct {
+	path   string // Empty keeps tokens in memory only
+	logger *slog.Logger
+	mu     sync.Mutex
+	tokens []APIToken
+}
+
+// NewTokenStore loads the tokens saved at path. An empty path keeps tokens in
+// memory, so they are lost on restart.
+func NewTokenStore(path string, logger *slog.Logger) (*TokenStore, error) {
+	s := &TokenStore{path: path, logger: logger}
+	if path == "" {
+		logger.Warn("API_TOKEN_FILE and CACHE_DIR are unset, API tokens will not survive a restart")
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API tokens: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse API tokens in %s: %w", path, err)
+	}
+	logger.Info("Loaded API tokens", "path", path, "count", len(s.tokens))
+	return s, nil
+}
+
+// Create mints a token for the user and returns it; it cannot be read back later
+func (s *TokenStore) Create(user *UserInfo, name string, scopes []string, expiresAt time.Time) (string, APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 64 {
+		return "", APIToken{}, errors.New("Give the token a name of at most 64 characters")
+	}
+	if len(scopes) == 0 {
+		return "", APIToken{}, errors.New("Choose at least one scope")
+	}
+	for _, scope := range scopes {
+		if scope != ScopeRead && scope != ScopeSend {
+			return "", APIToken{}, fmt.Errorf("Unknown scope %q", scope)
+		}
+	}
+	if !expiresAt.IsZero() && expiresAt.Before(time.Now()) {
+		return "", APIToken{}, errors.New("The expiry must be in the future")
+	}
+
+	secret := make([]byte, 32)
+	id := make([]byte, 8)
+	if _, err := rand.Read(secret); err != nil {
+		return "", APIToken{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	if _, err := rand.Read(id); err != nil {
+		return "", APIToken{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	plain := tokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	username := user.PreferredUsername
+	if username == "" {
+		username = user.Subject
+	}
+	token := APIToken{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Hash:      hashToken(plain),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+		Owner:     user.Subject,
+		OwnerName: username,
+		Groups:    user.Groups,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if t.Owner == token.Owner && t.Name == token.Name {
+			return "", APIToken{}, fmt.Errorf("You already have a token named %q", name)
+		}
+	}
+	s.tokens = append(s.tokens, token)
+	if err := s.save(); err != nil {
+		s.tokens = s.tokens[:len(s.tokens)-1]
+		return "", APIToken{}, err
+	}
+	return plain, token, nil
+}
+
+// List returns the user's tokens, oldest first
+func (s *TokenStore) List(owner string) []APIToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tokens []APIToken
+	for _, t := range s.tokens {
+		if t.Owner == owner {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// Revoke deletes one of the user's tokens, reporting whether it existed
+func (s *TokenStore) Revoke(owner, id string) (APIToken, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.tokens, func(t APIToken) bool { return t.Owner == owner && t.ID == id })
+	if i < 0 {
+		return APIToken{}, false, nil
+	}
+	token := s.tokens[i]
+	s.tokens = slices.Delete(s.tokens, i, i+1)
+	if err := s.save(); err != nil {
+		s.tokens = slices.Insert(s.tokens, i, token)
+		return APIToken{}, true, err
+	}
+	return token, true, nil
+}
+
+// Authenticate looks up an unexpired token and records its use
+func (s *TokenStore) Authenticate(plain string) (APIToken, bool) {
+	if !strings.HasPrefix(plain, tokenPrefix) {
+		return APIToken{}, false
+	}
+	hash := []byte(hashToken(plain))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.tokens {
+		t := &s.tokens[i]
+		if subtle.ConstantTimeCompare([]byte(t.Hash), hash) != 1 {
+			continue
+		}
+		if t.Expired() {
+			return APIToken{}, false
+		}
+		// Polling scripts would otherwise rewrite the file every few seconds
+		now := time.Now()
+		if now.Sub(t.LastUsed) >= lastUsedInterval {
+			t.LastUsed = now
+			if err := s.save(); err != nil {
+				s.logger.Warn("Failed to record API token use", "token", t.Name, "error", err)
+			}
+		}
+		return *t, true
+	}
+	return APIToken{}, false
+}
+
+// UpdateGroups refreshes the groups of the user's tokens, so that changes at
+// the provider reach the tokens at the user's next login or session renewal
+func (s *TokenStore) UpdateGroups(owner string, groups []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := false
+	for i := range s.tokens {
+		if s.tokens[i].Owner == owner && !slices.Equal(s.tokens[i].Groups, groups) {
+			s.tokens[i].Groups = groups
+			changed = true
+		}
+	}
+	if changed {
+		if err := s.save(); err != nil {
+			s.logger.Warn("Failed to update API token groups", "owner", owner, "error", err)
+		}
+	}
+}
+
+// save writes the tokens; the caller holds s.mu
+func (s *TokenStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := saveJSON(s.path, s.tokens); err != nil {
+		return fmt.Errorf("failed to save API tokens: %w", err)
+	}
+	return nil
+}
+
+// saveJSON replaces a file with v as indented JSON atomically, so a crash
+// mid-write leaves the previous file intact. The file is only readable by
+// its owner, as it holds credentials.
+func saveJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// hashToken returns the hex-encoded SHA-256 of a token. Tokens are random, so
+// a fast unsalted hash is enough.
+func hashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -1,17 +1,38 @@
 package cache
 
 import (
+	"context"
 	"sync"
 	"time"
 )
 
+// Failed loads are not retried until a backoff has passed, starting at
+// retryBackoffMin and doubling with each consecutive failure up to
+// retryBackoffMax, so an unreachable provider is not refetched on every request
+const (
+	retryBackoffMin = 10 * time.Second
+	retryBackoffMax = 5 * time.Minute
+)
+
 // Cache holds cached data with an expiration time
 type Cache[T any] struct {
 	data      *T // Use pointer to T to check for nil
 	expiresAt time.Time
+	lastErr   error        // Error from the most recent load, nil once one succeeds
+	failures  int          // Consecutive failed loads
+	retryAt   time.Time    // No load is started before this after a failure
+	loading   *call[T]     // In-flight load shared by concurrent callers
+	dirty     bool         // Changed since the last snapshot
 	mu        sync.RWMutex // Protects concurrent access
 }
 
+// call is a single run of a loader that several callers may wait on
+type call[T any] struct {
+	done chan struct{}
+	data T
+	err  error
+}
+
 // New creates a new Cache instance
 func New[T any]() *Cache[T] {
 	return &Cache[T]{}
@@ -36,6 +57,116 @@ func (c *Cache[T]) Set(data T, ttl time.Duration) {
 
 	c.data = &data
 	c.expiresAt = time.Now().Add(ttl)
+	c.lastErr = nil
+	c.failures = 0
+	c.retryAt = time.Time{}
+	c.dirty = true
+}
+
+// Peek retrieves the cached data even if it has expired
+func (c *Cache[T]) Peek() (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var zero T
+	if c.data == nil {
+		return zero, false
+	}
+	return *c.data, true
+}
+
+// Load returns the cached data, calling load to fill the cache on a miss.
+// Concurrent misses share a single call to load. Once the data expires it keeps
+// being served while one background refresh runs, and a failed refresh keeps
+// the last good data and records the error for LastError. An error is only
+// returned when there is no data to fall back on. After a failure no load is
+// started until the retry backoff has passed; until then a miss returns the
+// last error straight away.
+//
+// Loads are detached from ctx's cancellation so that one caller going away does
+// not fail the others waiting on it; load should bound its own duration.
+func (c *Cache[T]) Load(ctx context.Context, ttl time.Duration, load func(context.Context) (T, error)) (T, error) {
+	c.mu.Lock()
+	if c.data != nil {
+		data := *c.data
+		now := time.Now()
+		if now.After(c.expiresAt) && c.loading == nil && !now.Before(c.retryAt) {
+			c.start(ctx, ttl, load)
+		}
+		c.mu.Unlock()
+		return data, nil
+	}
+	pending := c.loading
+	if pending == nil {
+		if c.lastErr != nil && time.Now().Before(c.retryAt) {
+			err := c.lastErr
+			c.mu.Unlock()
+			var zero T
+			return zero, err
+		}
+		pending = c.start(ctx, ttl, load)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-pending.done:
+		return pending.data, pending.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// start runs load in the background and stores its result. c.mu must be held.
+func (c *Cache[T]) start(ctx context.Context, ttl time.Duration, load func(context.Context) (T, error)) *call[T] {
+	pending := &call[T]{done: make(chan struct{})}
+	c.loading = pending
+
+	go func() {
+		data, err := load(context.WithoutCancel(ctx))
+
+		c.mu.Lock()
+		// A Clear during the load discards its result
+		if c.loading == pending {
+			c.loading = nil
+			if err != nil {
+				c.lastErr = err
+				c.failures++
+				c.retryAt = time.Now().Add(retryBackoff(c.failures))
+			} else {
+				c.data = &data
+				c.expiresAt = time.Now().Add(ttl)
+				c.lastErr = nil
+				c.failures = 0
+				c.retryAt = time.Time{}
+				c.dirty = true
+			}
+		}
+		c.mu.Unlock()
+
+		pending.data, pending.err = data, err
+		close(pending.done)
+	}()
+
+	return pending
+}
+
+// retryBackoff is how long to wait before loading again after the given
+// number of consecutive failures
+func retryBackoff(failures int) time.Duration {
+	backoff := retryBackoffMin
+	for i := 1; i < failures && backoff < retryBackoffMax; i++ {
+		backoff *= 2
+	}
+	return min(backoff, retryBackoffMax)
+}
+
+// LastError returns the error from the most recent load, or nil if it succeeded.
+// A non-nil error alongside cached data means stale data is being served.
+func (c *Cache[T]) LastError() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastErr
 }
 
 // Clear removes all cached data
@@ -45,4 +176,52 @@ func (c *Cache[T]) Clear() {
 
 	c.data = nil
 	c.expiresAt = time.Time{} // Zero time, effectively expired
+	c.lastErr = nil
+	c.failures = 0
+	c.retryAt = time.Time{}
+	c.loading = nil
+	c.dirty = true
+}
+
+// cacheSnapshot is the form in which a Store saves a Cache
+type cacheSnapshot[T any] struct {
+	Data      *T
+	ExpiresAt time.Time
+}
+
+// Persist restores the cache from its snapshot in store, keeping the original
+// expiry, and saves it there from now on. Expired data is restored too, so Load
+// serves it while the first refresh runs. A nil store does nothing.
+func (c *Cache[T]) Persist(store *Store, name string) {
+	if store == nil {
+		return
+	}
+	var snap cacheSnapshot[T]
+	if !store.register(name, c, &snap) || snap.Data == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data == nil {
+		c.data = snap.Data
+		c.expiresAt = snap.ExpiresAt
+	}
+}
+
+func (c *Cache[T]) snapshot() (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil, false
+	}
+	c.dirty = false
+	return cacheSnapshot[T]{Data: c.data, ExpiresAt: c.expiresAt}, true
+}
+
+func (c *Cache[T]) markDirty() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dirty = true
 }
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadBacksOffAfterFailure(t *testing.T) {
+	c := New[string]()
+	var calls atomic.Int32
+	failing := func(context.Context) (string, error) {
+		calls.Add(1)
+		return "", errors.New("provider down")
+	}
+
+	if _, err := c.Load(context.Background(), time.Minute, failing); err == nil {
+		t.Fatal("expected the first load to fail")
+	}
+	// Within the backoff the error is returned without loading again
+	for range 3 {
+		if _, err := c.Load(context.Background(), time.Minute, failing); err == nil || err.Error() != "provider down" {
+			t.Fatalf("expected the recorded error, got %v", err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("load called %d times during the backoff, want 1", n)
+	}
+
+	// Clearing the cache, as the refresh button does, allows an immediate retry
+	c.Clear()
+	got, err := c.Load(context.Background(), time.Minute, func(context.Context) (string, error) { return "fresh", nil })
+	if err != nil || got != "fresh" {
+		t.Fatalf("Load after Clear = %q, %v", got, err)
+	}
+}
+
+func TestLoadServesStaleDataDuringBackoff(t *testing.T) {
+	c := New[string]()
+	c.Set("stale", -time.Second) // Already expired
+	var calls atomic.Int32
+	failing := func(context.Context) (string, error) {
+		calls.Add(1)
+		return "", errors.New("provider down")
+	}
+
+	if got, err := c.Load(context.Background(), time.Minute, failing); err != nil || got != "stale" {
+		t.Fatalf("Load = %q, %v; want the stale data", got, err)
+	}
+	// Wait for the background refresh to fail
+	deadline := time.Now().Add(time.Second)
+	for c.LastError() == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if c.LastError() == nil {
+		t.Fatal("background refresh did not record its error")
+	}
+
+	for range 3 {
+		if got, err := c.Load(context.Background(), time.Minute, failing); err != nil || got != "stale" {
+			t.Fatalf("Load = %q, %v; want the stale data", got, err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("load called %d times during the backoff, want 1", n)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		1:  retryBackoffMin,
+		2:  2 * retryBackoffMin,
+		3:  4 * retryBackoffMin,
+		20: retryBackoffMax,
+	} {
+		if got := retryBackoff(failures); got != want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Keyed holds per-key cached values, each with its own expiration time. When
+// full, the least recently used entry is evicted to make room.
+type Keyed[K comparable, V any] struct {
+	maxEntries int                 // Zero or less means unbounded
+	entries    map[K]*list.Element // Values are *entry[K, V]
+	lru        *list.List          // Most recently used at the front
+	hits       uint64
+	misses     uint64
+	evictions  uint64
+	dirty      bool       // Changed since the last snapshot
+	mu         sync.Mutex // Protects concurrent access; Get reorders the LRU list
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// Stats reports how a keyed cache has been used since it was created
+type Stats struct {
+	Entries   int    `json:"entries"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"` // Entries dropped to stay within the size bound
+}
+
+// NewKeyed creates a keyed cache holding at most maxEntries values
+func NewKeyed[K comparable, V any](maxEntries int) *Keyed[K, V] {
+	return &Keyed[K, V]{
+		maxEntries: maxEntries,
+		entries:    make(map[K]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Get retrieves the value for key if it exists and has not expired
+func (c *Keyed[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	el, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return zero, false
+	}
+	e := el.Value.(*entry[K, V])
+	if time.Now().After(e.expiresAt) {
+		c.remove(el)
+		c.misses++
+		return zero, false
+	}
+	c.lru.MoveToFront(el)
+	c.hits++
+	return e.value, true
+}
+
+// Set stores value under key with its own expiration duration, evicting the
+// least recently used entry if the cache is full
+func (c *Keyed[K, V]) Set(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value, e.expiresAt = value, expiresAt
+		c.lru.MoveToFront(el)
+		c.dirty = true
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+		c.evictions++
+	}
+	c.dirty = true
+}
+
+// Delete removes the value for key
+func (c *Keyed[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+		c.dirty = true
+	}
+}
+
+// Clear removes all cached values. The counters are kept.
+func (c *Keyed[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[K]*list.Element)
+	c.lru.Init()
+	c.dirty = true
+}
+
+// Stats returns the entry count and hit, miss and eviction counters
+func (c *Keyed[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Entries:   c.lru.Len(),
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
+
+// keyedSnapshot is the form in which a Store saves a Keyed cache
+type keyedSnapshot[K comparable, V any] struct {
+	Entries []entrySnapshot[K, V] // Most recently used first
+}
+
+type entrySnapshot[K comparable, V any] struct {
+	Key       K
+	Value     V
+	ExpiresAt time.Time
+}
+
+// Persist restores the unexpired entries from the cache's snapshot in store,
+// keeping their original expiry and recency, and saves the cache there from
+// now on. A nil store does nothing.
+func (c *Keyed[K, V]) Persist(store *Store, name string) {
+	if store == nil {
+		return
+	}
+	var snap keyedSnapshot[K, V]
+	if !store.register(name, c, &snap) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for _, e := range snap.Entries {
+		if _, exists := c.entries[e.Key]; exists || now.After(e.ExpiresAt) {
+			continue
+		}
+		if c.maxEntries > 0 && c.lru.Len() >= c.maxEntries {
+			break
+		}
+		c.entries[e.Key] = c.lru.PushBack(&entry[K, V]{key: e.Key, value: e.Value, expiresAt: e.ExpiresAt})
+	}
+}
+
+func (c *Keyed[K, V]) snapshot() (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil, false
+	}
+	c.dirty = false
+	snap := keyedSnapshot[K, V]{Entries: make([]entrySnapshot[K, V], 0, c.lru.Len())}
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*entry[K, V])
+		snap.Entries = append(snap.Entries, entrySnapshot[K, V]{Key: e.key, Value: e.value, ExpiresAt: e.expiresAt})
+	}
+	return snap, true
+}
+
+func (c *Keyed[K, V]) markDirty() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dirty = true
+}
+
+// remove unlinks an entry; c.mu must be held
+func (c *Keyed[K, V]) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*entry[K, V]).key)
+}
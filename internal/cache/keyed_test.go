@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// keys lists the cached keys from most to least recently used
+func keys(c *Keyed[string, int]) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var keys []string
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		keys = append(keys, el.Value.(*entry[string, int]).key)
+	}
+	return keys
+}
+
+func TestKeyed(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxEntries int
+		run        func(c *Keyed[string, int])
+		want       []string // Keys left, most recently used first
+		stats      Stats
+	}{
+		{
+			name:       "evicts least recently set",
+			maxEntries: 2,
+			run: func(c *Keyed[string, int]) {
+				c.Set("a", 1, time.Hour)
+				c.Set("b", 2, time.Hour)
+				c.Set("c", 3, time.Hour)
+			},
+			want:  []string{"c", "b"},
+			stats: Stats{Entries: 2, Evictions: 1},
+		},
+		{
+			name:       "get keeps an entry from eviction",
+			maxEntries: 2,
+			run: func(c *Keyed[string, int]) {
+				c.Set("a", 1, time.Hour)
+				c.Set("b", 2, time.Hour)
+				c.Get("a")
+				c.Set("c", 3, time.Hour)
+			},
+			want:  []string{"c", "a"},
+			stats: Stats{Entries: 2, Hits: 1, Evictions: 1},
+		},
+		{
+			name:       "set on existing key moves it to the front",
+			maxEntries: 2,
+			run: func(c *Keyed[string, int]) {
+				c.Set("a", 1, time.Hour)
+				c.Set("b", 2, time.Hour)
+				c.Set("a", 10, time.Hour)
+			},
+			want:  []string{"a", "b"},
+			stats: Stats{Entries: 2},
+		},
+		{
+			name: "expired entry is a miss",
+			run: func(c *Keyed[string, int]) {
+				c.Set("a", 1, -time.Second)
+				c.Set("b", 2, time.Hour)
+				c.Get("a")
+				c.Get("b")
+			},
+			want:  []string{"b"},
+			stats: Stats{Entries: 1, Hits: 1, Misses: 1},
+		},
+		{
+			name: "unknown key is a miss",
+			run: func(c *Keyed[string, int]) {
+				c.Get("a")
+				c.Set("a", 1, time.Hour)
+				c.Get("a")
+				c.Delete("a")
+				c.Get("a")
+			},
+			stats: Stats{Hits: 1, Misses: 2},
+		},
+		{
+			name: "unbounded",
+			run: func(c *Keyed[string, int]) {
+				for _, key := range []string{"a", "b", "c", "d"} {
+					c.Set(key, 0, time.Hour)
+				}
+			},
+			want:  []string{"d", "c", "b", "a"},
+			stats: Stats{Entries: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewKeyed[string, int](tt.maxEntries)
+			tt.run(c)
+			if got := keys(c); !slices.Equal(got, tt.want) {
+				t.Errorf("keys = %v, want %v", got, tt.want)
+			}
+			if got := c.Stats(); got != tt.stats {
+				t.Errorf("stats = %+v, want %+v", got, tt.stats)
+			}
+		})
+	}
+}
+
+func TestKeyedSetReplacesValue(t *testing.T) {
+	c := NewKeyed[string, int](1)
+	c.Set("a", 1, time.Hour)
+	c.Set("a", 2, time.Hour)
+	if got, ok := c.Get("a"); !ok || got != 2 {
+		t.Errorf("Get = %d, %v; want the replaced value", got, ok)
+	}
+	if evictions := c.Stats().Evictions; evictions != 0 {
+		t.Errorf("replacing a value evicted %d entries", evictions)
+	}
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Store saves snapshots of caches as gob files in a directory so they survive a
+// restart. Caches register with Persist, are restored straight away with their
+// original expiry, and are written back by Flush whenever they have changed.
+// A nil *Store disables persistence.
+type Store struct {
+	dir    string
+	mu     sync.Mutex // Serialises flushes
+	caches map[string]persisted
+}
+
+// persisted is a cache that can be snapshotted by a Store
+type persisted interface {
+	// snapshot returns the value to encode, or false if nothing changed since
+	// the last snapshot
+	snapshot() (any, bool)
+	// markDirty makes the next Flush write the cache again
+	markDirty()
+}
+
+// NewStore creates a store writing to dir, creating the directory if needed.
+// An empty dir returns a nil store, which persists nothing.
+func NewStore(dir string) (*Store, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &Store{dir: dir, caches: make(map[string]persisted)}, nil
+}
+
+// register adds a cache to be flushed under name and decodes its last snapshot
+// into into, reporting whether one was found
+func (s *Store) register(name string, c persisted, into any) bool {
+	s.mu.Lock()
+	s.caches[name] = c
+	s.mu.Unlock()
+
+	f, err := os.Open(s.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	if err != nil {
+		slog.Warn("Failed to open cache snapshot", "cache", name, "error", err)
+		return false
+	}
+	defer f.Close()
+
+	if err := gob.NewDecoder(f).Decode(into); err != nil {
+		// Usually a snapshot from an older version; it is replaced on the next flush
+		slog.Warn("Ignoring unreadable cache snapshot", "cache", name, "error", err)
+		return false
+	}
+	return true
+}
+
+// Flush writes every cache that changed since its last snapshot
+func (s *Store) Flush() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, c := range s.caches {
+		value, changed := c.snapshot()
+		if !changed {
+			continue
+		}
+		if err := s.write(name, value); err != nil {
+			slog.Warn("Failed to write cache snapshot", "cache", name, "error", err)
+			c.markDirty()
+		}
+	}
+}
+
+// Run flushes the store every interval
+func (s *Store) Run(interval time.Duration) {
+	if s == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.Flush()
+	}
+}
+
+// write replaces a snapshot atomically, so a crash mid-write leaves the previous
+// snapshot intact
+func (s *Store) write(name string, value any) error {
+	tmp, err := os.CreateTemp(s.dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if err := gob.NewEncoder(tmp).Encode(value); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(name))
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name+".gob")
+}
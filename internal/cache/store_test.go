@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T, dir string) *Store {
+	t.Helper()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	return store
+}
+
+func TestNewStoreWithoutDirectory(t *testing.T) {
+	store, err := NewStore("")
+	if store != nil || err != nil {
+		t.Fatalf("NewStore(\"\") = %v, %v; want a nil store", store, err)
+	}
+	// A nil store persists nothing and must not panic
+	New[string]().Persist(store, "cache")
+	NewKeyed[string, int](0).Persist(store, "keyed")
+	store.Flush()
+}
+
+func TestCacheRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := newTestStore(t, dir)
+	c := New[[]string]()
+	c.Persist(store, "streams")
+	c.Set([]string{"one", "two"}, time.Hour)
+	c.mu.RLock()
+	expiresAt := c.expiresAt
+	c.mu.RUnlock()
+	store.Flush()
+
+	restored := New[[]string]()
+	restored.Persist(newTestStore(t, dir), "streams")
+	got, ok := restored.Get()
+	if !ok || !slices.Equal(got, []string{"one", "two"}) {
+		t.Fatalf("Get after reload = %v, %v", got, ok)
+	}
+	if !restored.expiresAt.Equal(expiresAt) {
+		t.Errorf("expiry after reload = %v, want the original %v", restored.expiresAt, expiresAt)
+	}
+}
+
+func TestCacheExpiredSnapshotIsStale(t *testing.T) {
+	dir := t.TempDir()
+	store := newTestStore(t, dir)
+	c := New[string]()
+	c.Persist(store, "guide")
+	c.Set("old guide", -time.Minute)
+	store.Flush()
+
+	restored := New[string]()
+	restored.Persist(newTestStore(t, dir), "guide")
+	if _, ok := restored.Get(); ok {
+		t.Error("Get served an expired snapshot as fresh")
+	}
+	// It is still restored, so Load can serve it while refreshing
+	if got, ok := restored.Peek(); !ok || got != "old guide" {
+		t.Errorf("Peek after reload = %q, %v; want the stale data", got, ok)
+	}
+}
+
+func TestKeyedRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := newTestStore(t, dir)
+	c := NewKeyed[string, int](0)
+	c.Persist(store, "episodes")
+	c.Set("expired", 0, -time.Minute)
+	c.Set("a", 1, time.Hour)
+	c.Set("b", 2, time.Hour)
+	c.Get("a")
+	store.Flush()
+
+	restored := NewKeyed[string, int](0)
+	restored.Persist(newTestStore(t, dir), "episodes")
+	if got := keys(restored); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("keys after reload = %v, want the unexpired entries in recency order", got)
+	}
+	if got, ok := restored.Get("b"); !ok || got != 2 {
+		t.Errorf("Get(b) after reload = %d, %v", got, ok)
+	}
+}
+
+func TestKeyedRestoreKeepsBound(t *testing.T) {
+	dir := t.TempDir()
+	store := newTestStore(t, dir)
+	c := NewKeyed[string, int](0)
+	c.Persist(store, "episodes")
+	for _, key := range []string{"a", "b", "c"} {
+		c.Set(key, 0, time.Hour)
+	}
+	store.Flush()
+
+	restored := NewKeyed[string, int](2)
+	restored.Persist(newTestStore(t, dir), "episodes")
+	if got := keys(restored); !slices.Equal(got, []string{"c", "b"}) {
+		t.Errorf("keys after reload = %v, want the two most recently used", got)
+	}
+}
+
+func TestFlushWritesOnlyChangedCaches(t *testing.T) {
+	dir := t.TempDir()
+	store := newTestStore(t, dir)
+	c := New[string]()
+	c.Persist(store, "streams")
+
+	store.Flush()
+	if _, err := os.Stat(store.path("streams")); !os.IsNotExist(err) {
+		t.Fatalf("unchanged cache was written: %v", err)
+	}
+
+	c.Set("data", time.Hour)
+	store.Flush()
+	if _, err := os.Stat(store.path("streams")); err != nil {
+		t.Fatalf("changed cache was not written: %v", err)
+	}
+	if err := os.WriteFile(store.path("streams"), []byte("replaced"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store.Flush()
+	if data, _ := os.ReadFile(store.path("streams")); string(data) != "replaced" {
+		t.Error("unchanged cache was written again")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only the snapshot", len(entries))
+	}
+}
+
+func TestUnreadableSnapshotIgnored(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents []byte // Nil for no file
+	}{
+		{name: "missing"},
+		{name: "corrupt", contents: []byte("not a gob stream")},
+		{name: "empty", contents: []byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := newTestStore(t, dir)
+			if tt.contents != nil {
+				if err := os.WriteFile(store.path("streams"), tt.contents, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c := New[string]()
+			c.Persist(store, "streams")
+			if _, ok := c.Peek(); ok {
+				t.Fatal("cache restored from an unreadable snapshot")
+			}
+
+			// The cache is still registered, and its next change replaces the file
+			c.Set("fresh", time.Hour)
+			store.Flush()
+			restored := New[string]()
+			restored.Persist(newTestStore(t, dir), "streams")
+			if got, ok := restored.Get(); !ok || got != "fresh" {
+				t.Errorf("Get after reload = %q, %v", got, ok)
+			}
+		})
+	}
+}
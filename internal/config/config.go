@@ -3,7 +3,14 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 )
 
 // Config holds the configuration for the application
@@ -11,67 +18,266 @@ type Config struct {
 	XtreamBaseURL  string
 	XtreamUsername string
 	XtreamPassword string
+	XtreamTimezone string
 	DiscordWebhook string
 	CommandPrefix  string
+	OutputFormat   string // Default live output format: "ts" or "m3u8"
 	Port           string
 	BasePath       string
-	// Authentik OIDC configuration
-	AuthentikURL       string
+	// OIDC configuration
+	OIDCIssuerURL      string   // Issuer whose /.well-known/openid-configuration is discovered
+	OIDCScopes         []string // Requested scopes, always including openid
+	AuthentikURL       string   // Builds the issuer URL of an Authentik application when OIDCIssuerURL is unset
+	AuthentikAppSlug   string
 	ClientID           string
 	ClientSecret       string
 	RedirectURL        string
 	SessionSecret      string
 	DisableAuth        bool
 	DisableEpgPrefetch bool
+	// Authorization: the ID token claim holding the user's groups or roles, and
+	// the groups granted each permission ("view", "send", "send:<target>",
+	// "refresh" and "admin"). With no grants every signed-in user may do anything.
+	GroupsClaim string
+	Permissions map[string][]string
+	// JSON file holding the hashes of personal API tokens; defaults to a file
+	// in CacheDir, and tokens are kept in memory when both are empty
+	APITokenFile string
+	// JSON file keeping sign-ins across restarts; defaults to a file in
+	// CacheDir, and sessions are kept in memory when both are empty
+	SessionFile string
+	// Sessions end after this long without a request, and this long after
+	// sign-in however active they are
+	SessionIdleTimeout time.Duration
+	SessionLifetime    time.Duration
+	// Days before subscription expiry at which to start warning
+	AccountExpiryWarningDays int
+	// Upstream request limits: per-attempt timeouts, and retries of network
+	// errors and 5xx responses with exponential backoff starting at RetryBackoff
+	UpstreamTimeout time.Duration
+	XmltvTimeout    time.Duration
+	UpstreamRetries int
+	RetryBackoff    time.Duration
+	// EPG prefetch selection
+	EpgPrefetchAll                  bool
+	EpgPrefetchStreamIDs            []string
+	EpgPrefetchCategoryIDs          []string
+	EpgPrefetchExcludeCategoryIDs   []string
+	EpgPrefetchCategoryRegex        string
+	EpgPrefetchExcludeCategoryRegex string
+	// Most per-channel EPG responses kept per provider before the least recently
+	// used are evicted
+	EpgCacheSize int
+	// Directory for cache snapshots that survive restarts; empty disables them
+	CacheDir string
+	// Discord embed layout: whether embeds are sent, their parts in order and colour
+	DiscordEmbeds      bool
+	DiscordEmbedFields []string
+	DiscordEmbedColor  int
+	// Xtream providers, sorted by priority with the primary first
+	XtreamProviders []XtreamProvider
+	// Plain M3U playlists served alongside the Xtream providers
+	M3UPlaylists []M3UPlaylist
+	// Discord webhooks commands can be sent to; the first is the default
+	DiscordTargets []DiscordTarget
+	// Other services streams can be sent to, listed after the Discord targets
+	Sinks []SinkConfig
 }
 
+// XtreamProvider holds the credentials of a single Xtream Code provider
+type XtreamProvider struct {
+	Name     string // Namespaces the provider's channels; lowercase letters, digits, '-' and '_'
+	BaseURL  string
+	Username string
+	Password string
+	Timezone string
+	Priority int // Lower values are preferred when failing over
+}
+
+// M3UPlaylist describes a plain M3U/M3U8 playlist used as a channel source
+type M3UPlaylist struct {
+	Name   string // Namespaces the playlist's channels, like XtreamProvider.Name
+	Source string // Local file path or http(s) URL
+	EpgURL string // Optional XMLTV guide, defaults to the playlist's url-tvg header
+}
+
+// DiscordTarget is a webhook commands can be sent to, such as a bot's channel or thread
+type DiscordTarget struct {
+	Name      string // Shown in the target picker; same rules as XtreamProvider.Name
+	Webhook   string
+	Prefix    string // Command prefix, defaults to COMMAND_PREFIX
+	Username  string // Optional override of the webhook's display name
+	AvatarURL string // Optional override of the webhook's avatar
+	ThreadID  string // Optional thread within the webhook's channel to post in
+}
+
+// Sink types
+const (
+	SinkWebhook  = "webhook"  // Generic HTTP endpoint with a templated body
+	SinkSlack    = "slack"    // Slack incoming webhook
+	SinkMatrix   = "matrix"   // Matrix room, via the client-server API
+	SinkTelegram = "telegram" // Telegram chat, via the Bot API
+	SinkMpv      = "mpv"      // mpv player, via its JSON IPC socket
+	SinkKodi     = "kodi"     // Kodi player, via its HTTP JSON-RPC interface
+	SinkVLC      = "vlc"      // VLC player, via its Lua HTTP interface
+)
+
+// SinkConfig describes a non-Discord service or player streams can be sent to
+type SinkConfig struct {
+	Name      string // Shares the target picker with the Discord targets, so must not repeat their names
+	Type      string
+	URL       string // Webhook URL, Matrix homeserver, Telegram Bot API base URL, mpv socket, or Kodi or VLC web interface
+	Prefix    string // Command prefix, defaults to COMMAND_PREFIX
+	Template  string // webhook: text/template for the request body, JSON of the send when empty
+	Token     string // matrix: access token; telegram: bot token
+	Room      string // matrix: room ID, e.g. !abc:example.org
+	ChatID    string // telegram: chat ID or @channelusername
+	Username  string // slack: optional override of the webhook's name; kodi: web interface user
+	Password  string // kodi, vlc: web interface password
+	AvatarURL string // slack: optional override of the webhook's icon
+}
+
+// providerNamePattern restricts provider names to values safe in URLs and element IDs
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
 // LoadConfig reads the environment variables and returns a Config struct
 func LoadConfig() (*Config, error) {
 	cfg := &Config{
 		XtreamBaseURL:  os.Getenv("XTREAM_BASEURL"),
 		XtreamUsername: os.Getenv("XTREAM_USERNAME"),
 		XtreamPassword: os.Getenv("XTREAM_PASSWORD"),
+		XtreamTimezone: os.Getenv("XTREAM_TIMEZONE"),
 		DiscordWebhook: os.Getenv("DISCORD_WEBHOOK"),
 		CommandPrefix:  os.Getenv("COMMAND_PREFIX"),
+		OutputFormat:   os.Getenv("OUTPUT_FORMAT"),
 		Port:           os.Getenv("PORT"),
 		BasePath:       os.Getenv("BASE_PATH"),
-		// Authentik OIDC configuration
+		// OIDC configuration, accepting the older AUTHENTIK_* names
+		OIDCIssuerURL:      os.Getenv("OIDC_ISSUER_URL"),
+		OIDCScopes:         strings.Fields(strings.ReplaceAll(os.Getenv("OIDC_SCOPES"), ",", " ")), // Space or comma separated
 		AuthentikURL:       os.Getenv("AUTHENTIK_URL"),
-		ClientID:           os.Getenv("AUTHENTIK_CLIENT_ID"),
-		ClientSecret:       os.Getenv("AUTHENTIK_CLIENT_SECRET"),
-		RedirectURL:        os.Getenv("AUTHENTIK_REDIRECT_URL"),
+		AuthentikAppSlug:   os.Getenv("AUTHENTIK_APP_SLUG"),
+		ClientID:           firstEnv("OIDC_CLIENT_ID", "AUTHENTIK_CLIENT_ID"),
+		ClientSecret:       firstEnv("OIDC_CLIENT_SECRET", "AUTHENTIK_CLIENT_SECRET"),
+		RedirectURL:        firstEnv("OIDC_REDIRECT_URL", "AUTHENTIK_REDIRECT_URL"),
 		SessionSecret:      os.Getenv("SESSION_SECRET"),
+		GroupsClaim:        os.Getenv("AUTHZ_GROUPS_CLAIM"),
+		APITokenFile:       os.Getenv("API_TOKEN_FILE"),
+		SessionFile:        os.Getenv("SESSION_FILE"),
 		DisableAuth:        os.Getenv("DISABLE_AUTH") == "true",
 		DisableEpgPrefetch: os.Getenv("DISABLE_EPG_PREFETCH") == "true",
+		CacheDir:           os.Getenv("CACHE_DIR"),
+		DiscordEmbeds:      os.Getenv("DISCORD_EMBEDS") != "false",
+		DiscordEmbedFields: splitList(os.Getenv("DISCORD_EMBED_FIELDS")),
+		// EPG prefetch selection
+		EpgPrefetchAll:                  os.Getenv("EPG_PREFETCH_ALL") == "true",
+		EpgPrefetchStreamIDs:            splitList(os.Getenv("EPG_PREFETCH_STREAM_IDS")),
+		EpgPrefetchCategoryIDs:          splitList(os.Getenv("EPG_PREFETCH_CATEGORY_IDS")),
+		EpgPrefetchExcludeCategoryIDs:   splitList(os.Getenv("EPG_PREFETCH_EXCLUDE_CATEGORY_IDS")),
+		EpgPrefetchCategoryRegex:        os.Getenv("EPG_PREFETCH_CATEGORY_REGEX"),
+		EpgPrefetchExcludeCategoryRegex: os.Getenv("EPG_PREFETCH_EXCLUDE_CATEGORY_REGEX"),
 	}
 
 	// Validate required fields
-	if cfg.XtreamBaseURL == "" {
-		return nil, fmt.Errorf("XTREAM_BASEURL is required")
+	playlists, err := loadPlaylists()
+	if err != nil {
+		return nil, err
 	}
-	if cfg.XtreamUsername == "" {
-		return nil, fmt.Errorf("XTREAM_USERNAME is required")
+	cfg.M3UPlaylists = playlists
+	providers, err := loadProviders(cfg)
+	if err != nil {
+		return nil, err
 	}
-	if cfg.XtreamPassword == "" {
-		return nil, fmt.Errorf("XTREAM_PASSWORD is required")
+	cfg.XtreamProviders = providers
+	seen := make(map[string]bool)
+	for _, p := range providers {
+		seen[p.Name] = true
+	}
+	for _, p := range playlists {
+		if seen[p.Name] {
+			return nil, fmt.Errorf("M3U playlist %q has the same name as an Xtream provider", p.Name)
+		}
 	}
-	if cfg.DiscordWebhook == "" {
-		return nil, fmt.Errorf("DISCORD_WEBHOOK is required")
+
+	// Warn a week ahead of subscription expiry by default
+	cfg.AccountExpiryWarningDays = 7
+	if days := os.Getenv("ACCOUNT_EXPIRY_WARNING_DAYS"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("ACCOUNT_EXPIRY_WARNING_DAYS must be a non-negative number of days")
+		}
+		cfg.AccountExpiryWarningDays = n
+	}
+
+	// Bound upstream calls so a hung provider cannot block requests
+	if cfg.UpstreamTimeout, err = durationEnv("XTREAM_TIMEOUT", 15*time.Second); err != nil {
+		return nil, err
+	}
+	// The XMLTV guide can be tens of megabytes, so its download gets longer
+	if cfg.XmltvTimeout, err = durationEnv("XTREAM_XMLTV_TIMEOUT", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.RetryBackoff, err = durationEnv("XTREAM_RETRY_BACKOFF", 500*time.Millisecond); err != nil {
+		return nil, err
+	}
+	cfg.UpstreamRetries = 2
+	if retries := os.Getenv("XTREAM_RETRIES"); retries != "" {
+		n, err := strconv.Atoi(retries)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("XTREAM_RETRIES must be a non-negative number")
+		}
+		cfg.UpstreamRetries = n
+	}
+
+	cfg.EpgCacheSize = 10000
+	if size := os.Getenv("EPG_CACHE_SIZE"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("EPG_CACHE_SIZE must be a positive number of channels")
+		}
+		cfg.EpgCacheSize = n
+	}
+
+	// Validate EPG prefetch selection
+	for _, id := range cfg.EpgPrefetchStreamIDs {
+		if _, err := strconv.Atoi(id); err != nil {
+			return nil, fmt.Errorf("EPG_PREFETCH_STREAM_IDS contains an invalid stream ID %q", id)
+		}
+	}
+	if _, err := regexp.Compile(cfg.EpgPrefetchCategoryRegex); err != nil {
+		return nil, fmt.Errorf("EPG_PREFETCH_CATEGORY_REGEX is invalid: %w", err)
+	}
+	if _, err := regexp.Compile(cfg.EpgPrefetchExcludeCategoryRegex); err != nil {
+		return nil, fmt.Errorf("EPG_PREFETCH_EXCLUDE_CATEGORY_REGEX is invalid: %w", err)
 	}
 
 	// Only require auth config if auth is not disabled
 	if !cfg.DisableAuth {
-		if cfg.AuthentikURL == "" {
-			return nil, fmt.Errorf("AUTHENTIK_URL is required")
+		if cfg.OIDCIssuerURL == "" && cfg.AuthentikURL != "" {
+			// Authentik serves each application's issuer under its slug
+			if cfg.AuthentikAppSlug == "" {
+				cfg.AuthentikAppSlug = "go-media-control"
+			}
+			cfg.OIDCIssuerURL = fmt.Sprintf("%s/application/o/%s/", strings.TrimSuffix(cfg.AuthentikURL, "/"), cfg.AuthentikAppSlug)
+		}
+		if cfg.OIDCIssuerURL == "" {
+			return nil, fmt.Errorf("OIDC_ISSUER_URL is required")
 		}
 		if cfg.ClientID == "" {
-			return nil, fmt.Errorf("AUTHENTIK_CLIENT_ID is required")
+			return nil, fmt.Errorf("OIDC_CLIENT_ID is required")
 		}
 		if cfg.ClientSecret == "" {
-			return nil, fmt.Errorf("AUTHENTIK_CLIENT_SECRET is required")
+			return nil, fmt.Errorf("OIDC_CLIENT_SECRET is required")
 		}
 		if cfg.RedirectURL == "" {
-			return nil, fmt.Errorf("AUTHENTIK_REDIRECT_URL is required")
+			return nil, fmt.Errorf("OIDC_REDIRECT_URL is required")
+		}
+		if len(cfg.OIDCScopes) == 0 {
+			// offline_access asks for a refresh token, so sessions outlive the ID token
+			cfg.OIDCScopes = []string{"openid", "profile", "email", "offline_access"}
+		}
+		if !slices.Contains(cfg.OIDCScopes, "openid") {
+			cfg.OIDCScopes = append([]string{"openid"}, cfg.OIDCScopes...)
 		}
 		if cfg.SessionSecret == "" {
 			return nil, fmt.Errorf("SESSION_SECRET is required")
@@ -82,6 +288,74 @@ func LoadConfig() (*Config, error) {
 	if cfg.CommandPrefix == "" {
 		cfg.CommandPrefix = "!"
 	}
+	sinks, err := loadSinks(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Sinks = sinks
+	targets, err := loadTargets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DiscordTargets = targets
+	// Targets and sinks share the picker, so their names must be distinct
+	for _, t := range cfg.DiscordTargets {
+		for _, s := range cfg.Sinks {
+			if s.Name == t.Name {
+				return nil, fmt.Errorf("SINKS and DISCORD_TARGETS both use the name %q", s.Name)
+			}
+		}
+	}
+	// Authentik, Dex and Keycloak's group mapper all use the "groups" claim
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	permissions, err := loadPermissions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Permissions = permissions
+	if cfg.APITokenFile == "" && cfg.CacheDir != "" {
+		cfg.APITokenFile = filepath.Join(cfg.CacheDir, "api-tokens.json")
+	}
+	if cfg.SessionFile == "" && cfg.CacheDir != "" {
+		cfg.SessionFile = filepath.Join(cfg.CacheDir, "sessions.json")
+	}
+	if cfg.SessionIdleTimeout, err = durationEnv("SESSION_IDLE_TIMEOUT", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.SessionLifetime, err = durationEnv("SESSION_MAX_LIFETIME", 7*24*time.Hour); err != nil {
+		return nil, err
+	}
+
+	// Default to MPEG-TS output, which every provider supports
+	switch cfg.OutputFormat {
+	case "":
+		cfg.OutputFormat = "ts"
+	case "ts", "m3u8":
+	default:
+		return nil, fmt.Errorf("OUTPUT_FORMAT must be \"ts\" or \"m3u8\"")
+	}
+
+	// Embeds show the logo, guide and sender unless configured otherwise
+	if len(cfg.DiscordEmbedFields) == 0 {
+		cfg.DiscordEmbedFields = []string{"logo", "now", "next", "user"}
+	}
+	for _, field := range cfg.DiscordEmbedFields {
+		switch field {
+		case "logo", "now", "next", "user", "provider", "type":
+		default:
+			return nil, fmt.Errorf("DISCORD_EMBED_FIELDS contains an unknown field %q", field)
+		}
+	}
+	cfg.DiscordEmbedColor = 0x5865F2 // Discord blurple
+	if color := os.Getenv("DISCORD_EMBED_COLOR"); color != "" {
+		n, err := strconv.ParseUint(strings.TrimPrefix(color, "#"), 16, 24)
+		if err != nil {
+			return nil, fmt.Errorf("DISCORD_EMBED_COLOR must be a hex colour such as #5865F2")
+		}
+		cfg.DiscordEmbedColor = int(n)
+	}
 
 	// Set the default port if not provided
 	if cfg.Port == "" {
@@ -103,3 +377,325 @@ func LoadConfig() (*Config, error) {
 
 	return cfg, nil
 }
+
+// loadProviders reads the Xtream providers. XTREAM_PROVIDERS lists provider names,
+// each configured through XTREAM_<NAME>_BASEURL, _USERNAME, _PASSWORD, _TIMEZONE and
+// _PRIORITY. Without it the single XTREAM_BASEURL provider is used, named "default",
+// which may be left out when M3U playlists are configured instead.
+func loadProviders(cfg *Config) ([]XtreamProvider, error) {
+	names := splitList(os.Getenv("XTREAM_PROVIDERS"))
+	if len(names) == 0 {
+		if cfg.XtreamBaseURL == "" && len(cfg.M3UPlaylists) > 0 {
+			return nil, nil
+		}
+		if cfg.XtreamBaseURL == "" {
+			return nil, fmt.Errorf("XTREAM_BASEURL is required")
+		}
+		if cfg.XtreamUsername == "" {
+			return nil, fmt.Errorf("XTREAM_USERNAME is required")
+		}
+		if cfg.XtreamPassword == "" {
+			return nil, fmt.Errorf("XTREAM_PASSWORD is required")
+		}
+		if cfg.XtreamTimezone != "" {
+			if _, err := time.LoadLocation(cfg.XtreamTimezone); err != nil {
+				return nil, fmt.Errorf("XTREAM_TIMEZONE is not a valid timezone: %w", err)
+			}
+		}
+		return []XtreamProvider{{
+			Name:     "default",
+			BaseURL:  cfg.XtreamBaseURL,
+			Username: cfg.XtreamUsername,
+			Password: cfg.XtreamPassword,
+			Timezone: cfg.XtreamTimezone,
+		}}, nil
+	}
+
+	providers := make([]XtreamProvider, 0, len(names))
+	seen := make(map[string]bool)
+	for i, name := range names {
+		name = strings.ToLower(name)
+		if !providerNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("XTREAM_PROVIDERS contains an invalid provider name %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("XTREAM_PROVIDERS lists provider %q more than once", name)
+		}
+		seen[name] = true
+
+		prefix := "XTREAM_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		p := XtreamProvider{
+			Name:     name,
+			BaseURL:  os.Getenv(prefix + "BASEURL"),
+			Username: os.Getenv(prefix + "USERNAME"),
+			Password: os.Getenv(prefix + "PASSWORD"),
+			Timezone: os.Getenv(prefix + "TIMEZONE"),
+			Priority: i, // Listing order unless overridden
+		}
+		if p.BaseURL == "" {
+			return nil, fmt.Errorf("%sBASEURL is required", prefix)
+		}
+		if p.Username == "" {
+			return nil, fmt.Errorf("%sUSERNAME is required", prefix)
+		}
+		if p.Password == "" {
+			return nil, fmt.Errorf("%sPASSWORD is required", prefix)
+		}
+		if p.Timezone == "" {
+			p.Timezone = cfg.XtreamTimezone
+		}
+		if p.Timezone != "" {
+			if _, err := time.LoadLocation(p.Timezone); err != nil {
+				return nil, fmt.Errorf("%sTIMEZONE is not a valid timezone: %w", prefix, err)
+			}
+		}
+		if priority := os.Getenv(prefix + "PRIORITY"); priority != "" {
+			n, err := strconv.Atoi(priority)
+			if err != nil {
+				return nil, fmt.Errorf("%sPRIORITY must be a number", prefix)
+			}
+			p.Priority = n
+		}
+		providers = append(providers, p)
+	}
+
+	sort.SliceStable(providers, func(i, j int) bool {
+		return providers[i].Priority < providers[j].Priority
+	})
+
+	return providers, nil
+}
+
+// loadTargets reads the Discord send targets. DISCORD_TARGETS lists target names,
+// each configured through DISCORD_<NAME>_WEBHOOK and optionally _PREFIX, _USERNAME,
+// _AVATAR_URL and _THREAD_ID. Without it the single DISCORD_WEBHOOK is used, named
+// "default", which may be left out when other sinks are configured instead.
+func loadTargets(cfg *Config) ([]DiscordTarget, error) {
+	names := splitList(os.Getenv("DISCORD_TARGETS"))
+	if len(names) == 0 {
+		if cfg.DiscordWebhook == "" && len(cfg.Sinks) > 0 {
+			return nil, nil
+		}
+		if cfg.DiscordWebhook == "" {
+			return nil, fmt.Errorf("DISCORD_WEBHOOK is required")
+		}
+		return []DiscordTarget{{
+			Name:    "default",
+			Webhook: cfg.DiscordWebhook,
+			Prefix:  cfg.CommandPrefix,
+		}}, nil
+	}
+
+	targets := make([]DiscordTarget, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if !providerNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("DISCORD_TARGETS contains an invalid target name %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("DISCORD_TARGETS lists target %q more than once", name)
+		}
+		seen[name] = true
+
+		prefix := "DISCORD_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		t := DiscordTarget{
+			Name:      name,
+			Webhook:   os.Getenv(prefix + "WEBHOOK"),
+			Prefix:    os.Getenv(prefix + "PREFIX"),
+			Username:  os.Getenv(prefix + "USERNAME"),
+			AvatarURL: os.Getenv(prefix + "AVATAR_URL"),
+			ThreadID:  os.Getenv(prefix + "THREAD_ID"),
+		}
+		if t.Webhook == "" {
+			return nil, fmt.Errorf("%sWEBHOOK is required", prefix)
+		}
+		if t.Prefix == "" {
+			t.Prefix = cfg.CommandPrefix
+		}
+		if t.ThreadID != "" {
+			if _, err := strconv.ParseUint(t.ThreadID, 10, 64); err != nil {
+				return nil, fmt.Errorf("%sTHREAD_ID must be a numeric thread ID", prefix)
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// loadSinks reads the non-Discord sinks. SINKS lists sink names, each configured
+// through SINK_<NAME>_TYPE and the settings that type needs: _URL, _PREFIX,
+// _TEMPLATE, _TOKEN, _ROOM, _CHAT_ID, _USERNAME, _PASSWORD and _AVATAR_URL.
+func loadSinks(cfg *Config) ([]SinkConfig, error) {
+	var sinks []SinkConfig
+	seen := make(map[string]bool)
+	for _, name := range splitList(os.Getenv("SINKS")) {
+		name = strings.ToLower(name)
+		if !providerNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("SINKS contains an invalid sink name %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("SINKS lists sink %q more than once", name)
+		}
+		seen[name] = true
+
+		prefix := "SINK_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		s := SinkConfig{
+			Name:      name,
+			Type:      strings.ToLower(os.Getenv(prefix + "TYPE")),
+			URL:       os.Getenv(prefix + "URL"),
+			Prefix:    os.Getenv(prefix + "PREFIX"),
+			Template:  os.Getenv(prefix + "TEMPLATE"),
+			Token:     os.Getenv(prefix + "TOKEN"),
+			Room:      os.Getenv(prefix + "ROOM"),
+			ChatID:    os.Getenv(prefix + "CHAT_ID"),
+			Username:  os.Getenv(prefix + "USERNAME"),
+			Password:  os.Getenv(prefix + "PASSWORD"),
+			AvatarURL: os.Getenv(prefix + "AVATAR_URL"),
+		}
+		if s.Prefix == "" {
+			s.Prefix = cfg.CommandPrefix
+		}
+
+		var required []string
+		switch s.Type {
+		case SinkWebhook:
+			required = []string{"URL"}
+			if s.Template != "" {
+				// json is provided by the sink package when the template is used
+				funcs := template.FuncMap{"json": func(any) (string, error) { return "", nil }}
+				if _, err := template.New(name).Funcs(funcs).Parse(s.Template); err != nil {
+					return nil, fmt.Errorf("%sTEMPLATE is invalid: %w", prefix, err)
+				}
+			}
+		case SinkSlack:
+			required = []string{"URL"}
+		case SinkMatrix:
+			required = []string{"URL", "TOKEN", "ROOM"}
+		case SinkTelegram:
+			required = []string{"TOKEN", "CHAT_ID"}
+			if s.URL == "" {
+				s.URL = "https://api.telegram.org"
+			}
+		case SinkMpv, SinkKodi:
+			required = []string{"URL"}
+		case SinkVLC:
+			// VLC refuses HTTP control without a password
+			required = []string{"URL", "PASSWORD"}
+		case "":
+			return nil, fmt.Errorf("%sTYPE is required", prefix)
+		default:
+			return nil, fmt.Errorf("%sTYPE must be webhook, slack, matrix, telegram, mpv, kodi or vlc", prefix)
+		}
+		for _, setting := range required {
+			if os.Getenv(prefix+setting) == "" {
+				return nil, fmt.Errorf("%s%s is required", prefix, setting)
+			}
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// loadPermissions reads the groups granted each permission from AUTHZ_VIEW_GROUPS,
+// AUTHZ_SEND_GROUPS, AUTHZ_REFRESH_GROUPS and AUTHZ_ADMIN_GROUPS, and sending to a
+// single target from AUTHZ_SEND_<TARGET>_GROUPS. Targets must be loaded first.
+func loadPermissions(cfg *Config) (map[string][]string, error) {
+	permissions := make(map[string][]string)
+	for _, permission := range []string{"view", "send", "refresh", "admin"} {
+		if groups := splitList(os.Getenv("AUTHZ_" + strings.ToUpper(permission) + "_GROUPS")); len(groups) > 0 {
+			permissions[permission] = groups
+		}
+	}
+
+	targets := make(map[string]string) // Environment name to target name
+	for _, t := range cfg.DiscordTargets {
+		targets[strings.ToUpper(strings.ReplaceAll(t.Name, "-", "_"))] = t.Name
+	}
+	for _, s := range cfg.Sinks {
+		targets[strings.ToUpper(strings.ReplaceAll(s.Name, "-", "_"))] = s.Name
+	}
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		name, ok := strings.CutPrefix(key, "AUTHZ_SEND_")
+		if !ok {
+			continue
+		}
+		name, ok = strings.CutSuffix(name, "_GROUPS")
+		if !ok {
+			continue
+		}
+		// A misspelt target would otherwise silently grant nothing
+		target, known := targets[name]
+		if !known {
+			return nil, fmt.Errorf("%s does not name a send target", key)
+		}
+		if groups := splitList(value); len(groups) > 0 {
+			permissions["send:"+target] = groups
+		}
+	}
+	return permissions, nil
+}
+
+// loadPlaylists reads the M3U playlists. M3U_PLAYLISTS lists playlist names, each
+// configured through M3U_<NAME>_SOURCE and optionally M3U_<NAME>_EPG_URL.
+func loadPlaylists() ([]M3UPlaylist, error) {
+	var playlists []M3UPlaylist
+	seen := make(map[string]bool)
+	for _, name := range splitList(os.Getenv("M3U_PLAYLISTS")) {
+		name = strings.ToLower(name)
+		if !providerNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("M3U_PLAYLISTS contains an invalid playlist name %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("M3U_PLAYLISTS lists playlist %q more than once", name)
+		}
+		seen[name] = true
+
+		prefix := "M3U_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		p := M3UPlaylist{
+			Name:   name,
+			Source: os.Getenv(prefix + "SOURCE"),
+			EpgURL: os.Getenv(prefix + "EPG_URL"),
+		}
+		if p.Source == "" {
+			return nil, fmt.Errorf("%sSOURCE is required", prefix)
+		}
+		playlists = append(playlists, p)
+	}
+	return playlists, nil
+}
+
+// durationEnv reads a positive duration such as "10s" from the environment
+func durationEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration such as 10s", name)
+	}
+	return d, nil
+}
+
+// firstEnv returns the first of the named environment variables that is set
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// splitList splits a comma-separated environment value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
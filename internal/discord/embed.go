@@ -0,0 +1,95 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/git-saj/go-media-control/internal/sink"
+)
+
+// Embed is a Discord rich embed
+type Embed struct {
+	Title     string       `json:"title,omitempty"`
+	Color     int          `json:"color,omitempty"`
+	Thumbnail *EmbedImage  `json:"thumbnail,omitempty"`
+	Fields    []EmbedField `json:"fields,omitempty"`
+	Footer    *EmbedFooter `json:"footer,omitempty"`
+	Timestamp string       `json:"timestamp,omitempty"` // RFC 3339
+}
+
+// EmbedImage is an image shown in an embed
+type EmbedImage struct {
+	URL string `json:"url"`
+}
+
+// EmbedField is a name/value pair shown in an embed
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// EmbedFooter is the small text at the bottom of an embed
+type EmbedFooter struct {
+	Text string `json:"text"`
+}
+
+// EmbedLayout selects the parts of a NowPlaying that an embed shows
+type EmbedLayout struct {
+	// Parts in display order: "logo", "now", "next", "user", "provider" and "type"
+	Fields []string
+	Color  int
+}
+
+// Build renders a NowPlaying as an embed following the layout
+func (l EmbedLayout) Build(np sink.NowPlaying) Embed {
+	embed := Embed{
+		Title:     sink.Truncate(np.Title, 256), // Discord rejects over-long embeds
+		Color:     l.Color,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	for _, part := range l.Fields {
+		switch part {
+		case "logo":
+			// Discord only fetches thumbnails over http(s)
+			if strings.HasPrefix(np.Logo, "http://") || strings.HasPrefix(np.Logo, "https://") {
+				embed.Thumbnail = &EmbedImage{URL: np.Logo}
+			}
+		case "now":
+			if np.Current != nil {
+				name := "Now"
+				if np.Replay {
+					name = "Replay"
+				}
+				embed.Fields = append(embed.Fields, programmeField(name, np.Current))
+			}
+		case "next":
+			if np.Next != nil {
+				embed.Fields = append(embed.Fields, programmeField("Next", np.Next))
+			}
+		case "user":
+			if np.User != "" {
+				embed.Footer = &EmbedFooter{Text: "Sent by " + np.User}
+			}
+		case "provider":
+			if np.Provider != "" {
+				embed.Fields = append(embed.Fields, EmbedField{Name: "Provider", Value: np.Provider, Inline: true})
+			}
+		case "type":
+			if np.Type != "" {
+				embed.Fields = append(embed.Fields, EmbedField{Name: "Type", Value: np.Type, Inline: true})
+			}
+		}
+	}
+	return embed
+}
+
+// programmeField shows a programme with its times in each viewer's own timezone
+func programmeField(name string, p *sink.Programme) EmbedField {
+	return EmbedField{
+		Name:   name,
+		Value:  fmt.Sprintf("%s\n<t:%d:t> – <t:%d:t>", sink.Truncate(p.Title, 200), p.Start.Unix(), p.End.Unix()),
+		Inline: true,
+	}
+}
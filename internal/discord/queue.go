@@ -0,0 +1,159 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/git-saj/go-media-control/internal/sink"
+)
+
+const (
+	queueSize         = 100
+	deliveryWait      = 3 * time.Second // How long a sender waits before the message is reported as queued
+	maxRetries        = 3               // Retries of network errors and 5xx responses
+	maxRateLimitWaits = 10              // 429 responses tolerated for one message
+)
+
+// retryBackoff is the first wait before retrying a failed delivery, doubling
+// with each retry; tests shorten it
+var retryBackoff = time.Second
+
+// delivery is a queued message and the channel its outcome is reported on
+type delivery struct {
+	msg    Message
+	result chan error // Buffered so the worker never blocks on a sender that stopped waiting
+}
+
+// SendMessage queues a payload for the webhook and waits briefly for it to be
+// delivered. Messages are delivered one at a time in the order they were queued.
+func (c *WebhookClient) SendMessage(ctx context.Context, msg Message) (sink.Result, error) {
+	if msg.Username == "" {
+		msg.Username = c.target.Username
+	}
+	if msg.AvatarURL == "" {
+		msg.AvatarURL = c.target.AvatarURL
+	}
+	d := &delivery{msg: msg, result: make(chan error, 1)}
+	select {
+	case c.queue <- d:
+	default:
+		return sink.Dropped, fmt.Errorf("Webhook delivery queue is full")
+	}
+
+	timer := time.NewTimer(deliveryWait)
+	defer timer.Stop()
+	select {
+	case err := <-d.result:
+		if err != nil {
+			return sink.Dropped, err
+		}
+		return sink.Delivered, nil
+	case <-timer.C:
+		return sink.Queued, nil
+	case <-ctx.Done():
+		return sink.Queued, nil
+	}
+}
+
+// deliver sends queued messages until the client is discarded
+func (c *WebhookClient) deliver() {
+	for d := range c.queue {
+		err := c.post(d.msg)
+		if err != nil {
+			slog.Warn("Discord message dropped", "target", c.target.Name, "error", err)
+		}
+		d.result <- err
+	}
+}
+
+// post delivers one message, waiting out rate limits and retrying transient
+// failures with exponential backoff
+func (c *WebhookClient) post(msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal webhook payload: %w", err)
+	}
+
+	retries, rateLimited := 0, 0
+	for {
+		// Wait for the bucket to refill when the last response used it up
+		if wait := time.Until(c.resetAt); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		resp, err := c.httpClient.Post(c.URL, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			if retries >= maxRetries {
+				return fmt.Errorf("Failed to send webhook request: %w", sink.WithoutURL(err))
+			}
+			time.Sleep(retryBackoff << retries)
+			retries++
+			continue
+		}
+		c.trackRateLimit(resp.Header)
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			resp.Body.Close()
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests:
+			wait := retryAfter(resp)
+			resp.Body.Close()
+			if rateLimited >= maxRateLimitWaits {
+				return fmt.Errorf("Webhook request still rate limited after %d attempts", rateLimited+1)
+			}
+			slog.Info("Discord rate limit hit, waiting", "target", c.target.Name, "retry_after", wait)
+			time.Sleep(wait)
+			rateLimited++
+		case resp.StatusCode >= 500:
+			resp.Body.Close()
+			if retries >= maxRetries {
+				return fmt.Errorf("Webhook request failed with status code %d", resp.StatusCode)
+			}
+			time.Sleep(retryBackoff << retries)
+			retries++
+		default:
+			// Other client errors, such as a deleted webhook, will not succeed on retry
+			resp.Body.Close()
+			return fmt.Errorf("Webhook request failed with status code %d", resp.StatusCode)
+		}
+	}
+}
+
+// trackRateLimit remembers when the webhook's bucket refills once a response
+// reports no requests remaining
+func (c *WebhookClient) trackRateLimit(header http.Header) {
+	if header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+	if resetAfter, err := strconv.ParseFloat(header.Get("X-RateLimit-Reset-After"), 64); err == nil {
+		c.resetAt = time.Now().Add(seconds(resetAfter))
+	}
+}
+
+// retryAfter reads how long a 429 response asks to wait, preferring the
+// precise retry_after in the body over the headers
+func retryAfter(resp *http.Response) time.Duration {
+	var body struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil && body.RetryAfter > 0 {
+		return seconds(body.RetryAfter)
+	}
+	for _, name := range []string{"Retry-After", "X-RateLimit-Reset-After"} {
+		if s, err := strconv.ParseFloat(resp.Header.Get(name), 64); err == nil && s > 0 {
+			return seconds(s)
+		}
+	}
+	return retryBackoff
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,178 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/git-saj/go-media-control/internal/config"
+	"github.com/git-saj/go-media-control/internal/sink"
+)
+
+func init() {
+	retryBackoff = time.Millisecond
+}
+
+// webhookServer answers each request with the next of responses, repeating
+// the last one, and records the messages it receives
+type webhookServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses []func(w http.ResponseWriter)
+	messages  []Message
+}
+
+func newWebhookServer(t *testing.T, responses ...func(w http.ResponseWriter)) *webhookServer {
+	t.Helper()
+	s := &webhookServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg Message
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &msg)
+
+		s.mu.Lock()
+		s.messages = append(s.messages, msg)
+		respond := s.responses[min(len(s.messages), len(s.responses))-1]
+		s.mu.Unlock()
+		respond(w)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.messages)
+}
+
+func status(code int) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) { w.WriteHeader(code) }
+}
+
+func rateLimited(retryAfter string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		io.WriteString(w, `{"message":"You are being rate limited.","retry_after":`+retryAfter+`,"global":false}`)
+	}
+}
+
+func newTestClient(url string) *WebhookClient {
+	return NewWebhookClient(config.DiscordTarget{Name: "lounge", Webhook: url, Prefix: "!play", Username: "Media"}, nil)
+}
+
+func TestDeliveryRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []func(w http.ResponseWriter)
+		attempts  int
+		wantErr   string // Empty for a delivered message
+	}{
+		{name: "delivered", responses: []func(http.ResponseWriter){status(http.StatusNoContent)}, attempts: 1},
+		{name: "rate limited then delivered", responses: []func(http.ResponseWriter){rateLimited("0.01"), rateLimited("0.01"), status(http.StatusNoContent)}, attempts: 3},
+		{name: "server error then delivered", responses: []func(http.ResponseWriter){status(http.StatusBadGateway), status(http.StatusNoContent)}, attempts: 2},
+		{name: "server errors exhaust retries", responses: []func(http.ResponseWriter){status(http.StatusInternalServerError)}, attempts: maxRetries + 1,
+			wantErr: "Webhook request failed with status code 500"},
+		{name: "rate limit waits exhausted", responses: []func(http.ResponseWriter){rateLimited("0.001")}, attempts: maxRateLimitWaits + 1,
+			wantErr: "Webhook request still rate limited after 11 attempts"},
+		{name: "client error not retried", responses: []func(http.ResponseWriter){status(http.StatusNotFound)}, attempts: 1,
+			wantErr: "Webhook request failed with status code 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newWebhookServer(t, tt.responses...)
+			err := newTestClient(srv.URL).post(Message{Content: "!play http://example/1.ts"})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("post failed: %v", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Fatalf("post error = %v, want %q", err, tt.wantErr)
+			}
+			if n := srv.attempts(); n != tt.attempts {
+				t.Errorf("server saw %d attempts, want %d", n, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		body   string
+		want   time.Duration
+	}{
+		{name: "body", body: `{"retry_after":1.5}`, header: http.Header{"Retry-After": {"3"}}, want: 1500 * time.Millisecond},
+		{name: "retry-after header", header: http.Header{"Retry-After": {"2"}}, want: 2 * time.Second},
+		{name: "reset-after header", header: http.Header{"X-Ratelimit-Reset-After": {"0.25"}}, want: 250 * time.Millisecond},
+		{name: "nothing", want: retryBackoff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			for name, values := range tt.header {
+				rec.Header()[name] = values
+			}
+			rec.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(rec, tt.body)
+			if got := retryAfter(rec.Result()); got != tt.want {
+				t.Errorf("retryAfter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendMessageQueuesInOrder(t *testing.T) {
+	srv := newWebhookServer(t, rateLimited("0.01"), status(http.StatusNoContent))
+	c := newTestClient(srv.URL)
+
+	result, err := c.SendMessage(context.Background(), Message{Content: "first"})
+	if err != nil || result != sink.Delivered {
+		t.Fatalf("SendMessage = %v, %v", result, err)
+	}
+	if result, err := c.SendMessage(context.Background(), Message{Content: "second"}); err != nil || result != sink.Delivered {
+		t.Fatalf("SendMessage = %v, %v", result, err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	var contents []string
+	for _, msg := range srv.messages {
+		contents = append(contents, msg.Content)
+		if msg.Username != "Media" {
+			t.Errorf("message sent as %q, want the target's username", msg.Username)
+		}
+	}
+	if len(contents) != 3 || contents[0] != "first" || contents[1] != "first" || contents[2] != "second" {
+		t.Errorf("server received %q, want the first message retried and then the second", contents)
+	}
+}
+
+func TestSendMessageReportsDropped(t *testing.T) {
+	srv := newWebhookServer(t, status(http.StatusUnauthorized))
+	result, err := newTestClient(srv.URL).SendMessage(context.Background(), Message{Content: "x"})
+	if result != sink.Dropped || err == nil {
+		t.Errorf("SendMessage = %v, %v; want dropped", result, err)
+	}
+}
+
+func TestDeliveryErrorHidesWebhookURL(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	webhook := srv.URL + "/api/webhooks/123/secret-token"
+	srv.Close()
+
+	err := newTestClient(webhook).post(Message{Content: "x"})
+	if err == nil {
+		t.Fatal("expected an error from a closed server")
+	}
+	if strings.Contains(err.Error(), srv.URL) || strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("error leaks the webhook URL: %v", err)
+	}
+}
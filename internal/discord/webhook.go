@@ -2,48 +2,76 @@
 package discord
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
 	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/git-saj/go-media-control/internal/config"
+	"github.com/git-saj/go-media-control/internal/sink"
 )
 
-// WebhookClient sends messages to a Discord webhook
+// WebhookClient sends messages to a Discord webhook through an ordered
+// delivery queue. It is the sink for Discord targets.
 type WebhookClient struct {
 	URL        string
+	target     config.DiscordTarget
 	httpClient *http.Client
+	layout     *EmbedLayout // Nil sends plain messages without embeds
+	queue      chan *delivery
+	resetAt    time.Time // When the rate limit bucket refills; used only by the delivery worker
 }
 
-// NewWebhookClient creates a new WebhookClient
-func NewWebhookClient(url string) *WebhookClient {
-	return &WebhookClient{
-		URL:        url,
-		httpClient: &http.Client{},
-	}
-}
+var _ sink.Sink = (*WebhookClient)(nil)
 
-// Message represents a Discord webhook payload
-type Message struct {
-	Content string `json:"content"`
+// NewWebhookClient creates a WebhookClient for a send target and starts its
+// delivery worker. A nil layout disables embeds.
+func NewWebhookClient(target config.DiscordTarget, layout *EmbedLayout) *WebhookClient {
+	c := &WebhookClient{
+		URL:        webhookURL(target),
+		target:     target,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		layout:     layout,
+		queue:      make(chan *delivery, queueSize),
+	}
+	go c.deliver()
+	return c
 }
 
-// Send sends a message to the Discord webhook
-func (c *WebhookClient) Send(content string) error {
-	msg := Message{Content: content}
-	payload, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("Failed to marshal webhook payload: %w", err)
+// webhookURL adds the target's thread to its webhook URL
+func webhookURL(target config.DiscordTarget) string {
+	if target.ThreadID == "" {
+		return target.Webhook
 	}
-
-	resp, err := c.httpClient.Post(c.URL, "application/json", bytes.NewBuffer(payload))
+	u, err := url.Parse(target.Webhook)
 	if err != nil {
-		return fmt.Errorf("Failed to send webhook request: %w", err)
+		return target.Webhook
 	}
-	defer resp.Body.Close()
+	q := u.Query()
+	q.Set("thread_id", target.ThreadID)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
 
-	if resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("Webhook request failed with status code %d", resp.StatusCode)
-	}
+// Name returns the target's name
+func (c *WebhookClient) Name() string {
+	return c.target.Name
+}
+
+// Message represents a Discord webhook payload
+type Message struct {
+	Content   string  `json:"content"`
+	Username  string  `json:"username,omitempty"`   // Overrides the webhook's display name
+	AvatarURL string  `json:"avatar_url,omitempty"` // Overrides the webhook's avatar
+	Embeds    []Embed `json:"embeds,omitempty"`
+}
 
-	return nil
+// Send sends the command for a stream with an embed describing it. The command
+// stays in the content so bots can still parse it.
+func (c *WebhookClient) Send(ctx context.Context, msg sink.Message) (sink.Result, error) {
+	payload := Message{Content: sink.Command(c.target.Prefix, msg.StreamURL)}
+	if c.layout != nil {
+		payload.Embeds = []Embed{c.layout.Build(msg.NowPlaying)}
+	}
+	return c.SendMessage(ctx, payload)
 }
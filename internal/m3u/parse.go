@@ -0,0 +1,120 @@
+package m3u
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/git-saj/go-media-control/internal/xtream"
+)
+
+// attrPattern matches key="value" attributes on #EXTM3U and #EXTINF lines
+var attrPattern = regexp.MustCompile(`([A-Za-z0-9_-]+)="([^"]*)"`)
+
+// Parsed is the content of a playlist
+type Parsed struct {
+	Items      []xtream.MediaItem
+	Categories []xtream.Category
+	GuideURL   string // XMLTV guide from the url-tvg/x-tvg-url header, if any
+}
+
+// Parse reads an M3U/M3U8 playlist. Channels are numbered from 1 in playlist
+// order, and each distinct group-title becomes a category numbered the same way.
+func Parse(r io.Reader) (*Parsed, error) {
+	parsed := &Parsed{}
+	groupIDs := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // Some EXTINF lines carry long attribute lists
+
+	var attrs map[string]string
+	var title string
+	pending := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTM3U"):
+			header := attributes(line)
+			guide := header["url-tvg"]
+			if guide == "" {
+				guide = header["x-tvg-url"]
+			}
+			// Several guides may be listed; the first one is used
+			parsed.GuideURL = strings.TrimSpace(strings.Split(guide, ",")[0])
+		case strings.HasPrefix(line, "#EXTINF:"):
+			attrs, title = parseExtinf(line)
+			pending = true
+		case strings.HasPrefix(line, "#EXTGRP:"):
+			if pending && attrs["group-title"] == "" {
+				attrs["group-title"] = strings.TrimSpace(strings.TrimPrefix(line, "#EXTGRP:"))
+			}
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			// A URL line completes the entry; bare URLs without #EXTINF are kept too
+			if !pending {
+				attrs, title = map[string]string{}, line
+			}
+			if title == "" {
+				title = attrs["tvg-name"]
+			}
+
+			categoryID := ""
+			if group := attrs["group-title"]; group != "" {
+				id, ok := groupIDs[group]
+				if !ok {
+					id = strconv.Itoa(len(groupIDs) + 1)
+					groupIDs[group] = id
+					parsed.Categories = append(parsed.Categories, xtream.Category{CategoryID: id, CategoryName: group})
+				}
+				categoryID = id
+			}
+
+			parsed.Items = append(parsed.Items, xtream.MediaItem{
+				Name:         title,
+				StreamID:     len(parsed.Items) + 1,
+				Logo:         attrs["tvg-logo"],
+				StreamURL:    line,
+				CategoryID:   categoryID,
+				EpgChannelID: attrs["tvg-id"],
+				StreamType:   xtream.StreamTypeLive,
+			})
+			pending = false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read playlist: %w", err)
+	}
+
+	return parsed, nil
+}
+
+// parseExtinf splits an #EXTINF line into its attributes and the title after the
+// first comma outside quotes
+func parseExtinf(line string) (map[string]string, string) {
+	body := strings.TrimPrefix(line, "#EXTINF:")
+	inQuotes := false
+	for i, r := range body {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ',' && !inQuotes:
+			return attributes(body[:i]), strings.TrimSpace(body[i+1:])
+		}
+	}
+	return attributes(body), ""
+}
+
+// attributes collects the key="value" pairs of a directive line
+func attributes(line string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attrPattern.FindAllStringSubmatch(line, -1) {
+		attrs[strings.ToLower(m[1])] = m[2]
+	}
+	return attrs
+}
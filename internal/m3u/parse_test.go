@@ -0,0 +1,100 @@
+package m3u
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/git-saj/go-media-control/internal/xtream"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		playlist   string
+		items      []xtream.MediaItem
+		categories []string // Category names in ID order
+		guideURL   string
+	}{
+		{
+			name: "extinf attributes",
+			playlist: `#EXTM3U url-tvg="http://guide.example/epg.xml, http://guide.example/other.xml"
+#EXTINF:-1 tvg-id="bbc1.uk" tvg-name="BBC One" tvg-logo="http://logo.example/bbc1.png" group-title="UK",BBC One HD
+http://stream.example/bbc1.m3u8
+#EXTINF:-1 TVG-ID="news.uk" group-title="News, Weather",Breaking News, Live
+http://stream.example/news.ts
+#EXTINF:-1 tvg-id="itv.uk" group-title="UK",ITV
+http://stream.example/itv.ts
+`,
+			items: []xtream.MediaItem{
+				{Name: "BBC One HD", StreamID: 1, Logo: "http://logo.example/bbc1.png", StreamURL: "http://stream.example/bbc1.m3u8", CategoryID: "1", EpgChannelID: "bbc1.uk", StreamType: xtream.StreamTypeLive},
+				{Name: "Breaking News, Live", StreamID: 2, StreamURL: "http://stream.example/news.ts", CategoryID: "2", EpgChannelID: "news.uk", StreamType: xtream.StreamTypeLive},
+				{Name: "ITV", StreamID: 3, StreamURL: "http://stream.example/itv.ts", CategoryID: "1", EpgChannelID: "itv.uk", StreamType: xtream.StreamTypeLive},
+			},
+			categories: []string{"UK", "News, Weather"},
+			guideURL:   "http://guide.example/epg.xml",
+		},
+		{
+			name:     "crlf line endings and byte order mark",
+			playlist: "\ufeff#EXTM3U x-tvg-url=\"http://guide.example/epg.xml\"\r\n#EXTINF:-1 tvg-id=\"bbc1.uk\",BBC One\r\nhttp://stream.example/bbc1.ts\r\n\r\n",
+			items: []xtream.MediaItem{
+				{Name: "BBC One", StreamID: 1, StreamURL: "http://stream.example/bbc1.ts", EpgChannelID: "bbc1.uk", StreamType: xtream.StreamTypeLive},
+			},
+			guideURL: "http://guide.example/epg.xml",
+		},
+		{
+			name: "missing header",
+			playlist: `#EXTINF:-1,BBC One
+http://stream.example/bbc1.ts
+`,
+			items: []xtream.MediaItem{
+				{Name: "BBC One", StreamID: 1, StreamURL: "http://stream.example/bbc1.ts", StreamType: xtream.StreamTypeLive},
+			},
+		},
+		{
+			name: "extgrp, tvg-name fallback and bare urls",
+			playlist: `#EXTM3U
+#EXTINF:-1 tvg-name="Film 4",
+#EXTGRP:Movies
+#EXTVLCOPT:http-user-agent=Player
+http://stream.example/film4.ts
+http://stream.example/bare.ts
+`,
+			items: []xtream.MediaItem{
+				{Name: "Film 4", StreamID: 1, StreamURL: "http://stream.example/film4.ts", CategoryID: "1", StreamType: xtream.StreamTypeLive},
+				{Name: "http://stream.example/bare.ts", StreamID: 2, StreamURL: "http://stream.example/bare.ts", StreamType: xtream.StreamTypeLive},
+			},
+			categories: []string{"Movies"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := Parse(strings.NewReader(tt.playlist))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(parsed.Items) != len(tt.items) {
+				t.Fatalf("parsed %d items, want %d: %+v", len(parsed.Items), len(tt.items), parsed.Items)
+			}
+			for i, want := range tt.items {
+				if parsed.Items[i] != want {
+					t.Errorf("item %d = %+v, want %+v", i, parsed.Items[i], want)
+				}
+			}
+			var categories []string
+			for i, category := range parsed.Categories {
+				if category.CategoryID != strconv.Itoa(i+1) {
+					t.Errorf("category %q has ID %s, want %d", category.CategoryName, category.CategoryID, i+1)
+				}
+				categories = append(categories, category.CategoryName)
+			}
+			if !slices.Equal(categories, tt.categories) {
+				t.Errorf("categories = %q, want %q", categories, tt.categories)
+			}
+			if parsed.GuideURL != tt.guideURL {
+				t.Errorf("guide URL = %q, want %q", parsed.GuideURL, tt.guideURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package m3u
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/git-saj/go-media-control/internal/cache"
+	"github.com/git-saj/go-media-control/internal/config"
+	"github.com/git-saj/go-media-control/internal/xtream"
+)
+
+// Playlist is a channel source backed by a plain M3U/M3U8 playlist, read from a
+// local file or an http(s) URL
+type Playlist struct {
+	name         string
+	source       string
+	epgURL       string
+	httpClient   *http.Client
+	timeout      time.Duration // Limit for reading the playlist
+	guideTimeout time.Duration // Limit for downloading the guide, which can be large
+	Cache        *cache.Cache[*Parsed]
+	GuideCache   *cache.Cache[map[string][]xtream.EpgListing]
+	mu           sync.RWMutex
+	streamURLs   map[int]string
+	names        map[int]string
+	tvgIDs       map[int]string
+	guideURL     string
+}
+
+var _ xtream.ChannelSource = (*Playlist)(nil)
+
+// NewPlaylist creates a playlist source from the configuration
+func NewPlaylist(cfg *config.Config, playlist config.M3UPlaylist) *Playlist {
+	return &Playlist{
+		name:         playlist.Name,
+		source:       playlist.Source,
+		epgURL:       playlist.EpgURL,
+		httpClient:   &http.Client{},
+		timeout:      cfg.UpstreamTimeout,
+		guideTimeout: cfg.XmltvTimeout,
+		Cache:        cache.New[*Parsed](),
+		GuideCache:   cache.New[map[string][]xtream.EpgListing](),
+		streamURLs:   make(map[int]string),
+		names:        make(map[int]string),
+		tvgIDs:       make(map[int]string),
+	}
+}
+
+// Name returns the playlist name, which namespaces its channels
+func (p *Playlist) Name() string {
+	return p.name
+}
+
+// open returns the playlist or guide at a local path or http(s) URL. Downloads,
+// including reading the body, are limited to timeout.
+func (p *Playlist) open(ctx context.Context, location string, timeout time.Duration) (io.ReadCloser, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.Open(location)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}, nil
+}
+
+// cancelOnClose releases a download's timeout once its body has been read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// load returns the parsed playlist. Once the cache expires the old playlist is
+// served while it is read again in the background.
+func (p *Playlist) load(ctx context.Context) (*Parsed, error) {
+	return p.Cache.Load(ctx, time.Minute*10, p.fetch)
+}
+
+// fetch reads and parses the playlist and indexes its channels
+func (p *Playlist) fetch(ctx context.Context) (*Parsed, error) {
+	body, err := p.open(ctx, p.source, p.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open playlist %s: %w", p.name, err)
+	}
+	defer body.Close()
+
+	parsed, err := Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse playlist %s: %w", p.name, err)
+	}
+	for i := range parsed.Items {
+		parsed.Items[i].Provider = p.name
+	}
+	slog.Info("M3U playlist loaded", "playlist", p.name, "channels", len(parsed.Items), "groups", len(parsed.Categories))
+	p.index(parsed)
+
+	return parsed, nil
+}
+
+// index records the channels' URLs and identities for sending, EPG and failover
+func (p *Playlist) index(parsed *Parsed) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.streamURLs = make(map[int]string, len(parsed.Items))
+	p.names = make(map[int]string, len(parsed.Items))
+	p.tvgIDs = make(map[int]string, len(parsed.Items))
+	for _, item := range parsed.Items {
+		p.streamURLs[item.StreamID] = item.StreamURL
+		p.names[item.StreamID] = item.Name
+		if item.EpgChannelID != "" {
+			p.tvgIDs[item.StreamID] = item.EpgChannelID
+		}
+	}
+	p.guideURL = parsed.GuideURL
+}
+
+// Persist restores the playlist and guide from their snapshots in store and
+// saves them there from now on. A nil store does nothing.
+func (p *Playlist) Persist(store *cache.Store) {
+	p.Cache.Persist(store, "m3u-"+p.name+"-playlist")
+	p.GuideCache.Persist(store, "m3u-"+p.name+"-guide")
+	if parsed, ok := p.Cache.Peek(); ok {
+		p.index(parsed)
+	}
+}
+
+// GetLiveStreams returns the playlist's channels
+func (p *Playlist) GetLiveStreams(ctx context.Context) ([]xtream.MediaItem, error) {
+	parsed, err := p.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Items, nil
+}
+
+// GetCategories returns the playlist's groups
+func (p *Playlist) GetCategories(ctx context.Context) ([]xtream.Category, error) {
+	parsed, err := p.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Categories, nil
+}
+
+// GetEpgForStream returns the guide listings for a channel, matched by tvg-id.
+// Channels are reported without EPG when the playlist has no guide.
+func (p *Playlist) GetEpgForStream(ctx context.Context, streamID int) ([]xtream.EpgListing, string, error) {
+	p.mu.RLock()
+	tvgID := p.tvgIDs[streamID]
+	guideURL := p.guideURL
+	p.mu.RUnlock()
+	if p.epgURL != "" {
+		guideURL = p.epgURL
+	}
+	if tvgID == "" || guideURL == "" {
+		return nil, "", nil
+	}
+
+	index, err := p.loadGuide(ctx, guideURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Return a copy to avoid modifying cache
+	return append([]xtream.EpgListing(nil), index[tvgID]...), "", nil
+}
+
+// loadGuide returns the XMLTV index. Concurrent callers share one download,
+// and once the cache expires the old guide is served while it is downloaded
+// again in the background.
+func (p *Playlist) loadGuide(ctx context.Context, guideURL string) (map[string][]xtream.EpgListing, error) {
+	return p.GuideCache.Load(ctx, time.Hour*24, func(ctx context.Context) (map[string][]xtream.EpgListing, error) {
+		return p.fetchGuide(ctx, guideURL)
+	})
+}
+
+// fetchGuide downloads and parses the XMLTV guide
+func (p *Playlist) fetchGuide(ctx context.Context, guideURL string) (map[string][]xtream.EpgListing, error) {
+	body, err := p.open(ctx, guideURL, p.guideTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch guide for playlist %s: %w", p.name, err)
+	}
+	defer body.Close()
+
+	index, err := xtream.ParseXMLTV(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse guide for playlist %s: %w", p.name, err)
+	}
+	slog.Info("M3U guide loaded", "playlist", p.name, "channels", len(index))
+
+	return index, nil
+}
+
+// CheckFormat accepts any format; playlist URLs are sent as listed
+func (p *Playlist) CheckFormat(ctx context.Context, format string) error {
+	return nil
+}
+
+// GetStreamURLWithFormat returns the channel's URL from the playlist. Playlists
+// carry a single URL per channel, so the format is ignored.
+func (p *Playlist) GetStreamURLWithFormat(streamID int, format string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	url, ok := p.streamURLs[streamID]
+	return url, ok
+}
+
+// ChannelIdentity returns the name and tvg-id of a channel
+func (p *Playlist) ChannelIdentity(streamID int) (string, string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	name, ok := p.names[streamID]
+	return name, p.tvgIDs[streamID], ok
+}
+
+// Healthy reports whether the playlist was read successfully last time
+func (p *Playlist) Healthy() bool {
+	return p.Cache.LastError() == nil
+}
+
+// ClearCache forces the playlist and its guide to be read again
+func (p *Playlist) ClearCache() {
+	p.Cache.Clear()
+	p.GuideCache.Clear()
+}
@@ -0,0 +1,152 @@
+package m3u
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+const testGuide = `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <programme start="20240101120000 +0000" stop="20240101130000 +0000" channel="bbc1.uk"><title>News</title></programme>
+  <programme start="20240101130000 +0000" stop="20240101140000 +0000" channel="bbc1.uk"><title>Weather</title></programme>
+</tv>`
+
+// guideServer serves the guide at /epg.xml, counting downloads, and answers
+// other paths with a 404
+func guideServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/epg.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		downloads.Add(1)
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(testGuide))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &downloads
+}
+
+// newTestPlaylist writes a playlist to a file and returns a source reading it
+func newTestPlaylist(t *testing.T, contents, epgURL string) *Playlist {
+	t.Helper()
+	path := t.TempDir() + "/playlist.m3u"
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{UpstreamTimeout: 5 * time.Second, XmltvTimeout: 5 * time.Second}
+	return NewPlaylist(cfg, config.M3UPlaylist{Name: "freetv", Source: path, EpgURL: epgURL})
+}
+
+func TestPlaylistGuide(t *testing.T) {
+	srv, downloads := guideServer(t)
+	p := newTestPlaylist(t, `#EXTM3U url-tvg="`+srv.URL+`/epg.xml"
+#EXTINF:-1 tvg-id="bbc1.uk",BBC One
+http://stream.example/bbc1.ts
+#EXTINF:-1 tvg-id="itv.uk",ITV
+http://stream.example/itv.ts
+#EXTINF:-1,No guide
+http://stream.example/none.ts
+`, "")
+
+	items, err := p.GetLiveStreams(context.Background())
+	if err != nil || len(items) != 3 || items[0].Provider != "freetv" {
+		t.Fatalf("GetLiveStreams = %+v, %v", items, err)
+	}
+
+	// Concurrent requests share one download of the guide
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			epg, _, err := p.GetEpgForStream(context.Background(), 1)
+			if err != nil || len(epg) != 2 || epg[0].Title != "News" {
+				t.Errorf("GetEpgForStream(1) = %+v, %v", epg, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("guide downloaded %d times, want 1", n)
+	}
+
+	// A channel missing from the guide, or without a tvg-id, has no listings
+	for _, streamID := range []int{2, 3} {
+		if epg, _, err := p.GetEpgForStream(context.Background(), streamID); err != nil || len(epg) != 0 {
+			t.Errorf("GetEpgForStream(%d) = %+v, %v", streamID, epg, err)
+		}
+	}
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("guide downloaded %d times, want it served from the cache", n)
+	}
+
+	// Clearing the cache, as the refresh button does, downloads it again
+	p.ClearCache()
+	if _, err := p.GetLiveStreams(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := p.GetEpgForStream(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := downloads.Load(); n != 2 {
+		t.Errorf("guide downloaded %d times after ClearCache, want 2", n)
+	}
+}
+
+func TestPlaylistGuideOverride(t *testing.T) {
+	srv, downloads := guideServer(t)
+	// The configured guide wins over the playlist's url-tvg header
+	p := newTestPlaylist(t, `#EXTM3U url-tvg="`+srv.URL+`/missing.xml"
+#EXTINF:-1 tvg-id="bbc1.uk",BBC One
+http://stream.example/bbc1.ts
+`, srv.URL+"/epg.xml")
+	if _, err := p.GetLiveStreams(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	epg, _, err := p.GetEpgForStream(context.Background(), 1)
+	if err != nil || len(epg) != 2 || downloads.Load() != 1 {
+		t.Errorf("GetEpgForStream = %+v, %v after %d downloads", epg, err, downloads.Load())
+	}
+}
+
+func TestPlaylistGuideFailure(t *testing.T) {
+	srv, _ := guideServer(t)
+	p := newTestPlaylist(t, `#EXTM3U url-tvg="`+srv.URL+`/missing.xml"
+#EXTINF:-1 tvg-id="bbc1.uk",BBC One
+http://stream.example/bbc1.ts
+`, "")
+	if _, err := p.GetLiveStreams(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := p.GetEpgForStream(context.Background(), 1); err == nil {
+		t.Error("GetEpgForStream succeeded without a guide")
+	}
+	// A missing guide does not make the playlist itself unhealthy
+	if !p.Healthy() {
+		t.Error("playlist reported unhealthy after a guide failure")
+	}
+}
+
+func TestPlaylistMissingSource(t *testing.T) {
+	cfg := &config.Config{UpstreamTimeout: time.Second, XmltvTimeout: time.Second}
+	p := NewPlaylist(cfg, config.M3UPlaylist{Name: "freetv", Source: t.TempDir() + "/missing.m3u"})
+	if _, err := p.GetLiveStreams(context.Background()); err == nil {
+		t.Fatal("GetLiveStreams succeeded without a playlist")
+	}
+	if p.Healthy() {
+		t.Error("playlist reported healthy after failing to load")
+	}
+}
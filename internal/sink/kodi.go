@@ -0,0 +1,182 @@
+package sink
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// Kodi drives a Kodi instance through its HTTP JSON-RPC interface, enabled
+// under Settings > Services > Control
+type Kodi struct {
+	name     string
+	url      string // JSON-RPC endpoint
+	username string
+	password string
+	client   *http.Client
+	nextID   atomic.Uint64
+	sent     sentTitles
+}
+
+var _ Player = (*Kodi)(nil)
+
+// NewKodi creates a Kodi sink. The URL may be the web interface's base URL or
+// its /jsonrpc endpoint.
+func NewKodi(cfg config.SinkConfig, client *http.Client) *Kodi {
+	url := strings.TrimSuffix(cfg.URL, "/")
+	if !strings.HasSuffix(url, "/jsonrpc") {
+		url += "/jsonrpc"
+	}
+	return &Kodi{name: cfg.Name, url: url, username: cfg.Username, password: cfg.Password, client: client}
+}
+
+// Name returns the sink's name
+func (k *Kodi) Name() string {
+	return k.name
+}
+
+// Send opens the stream in Kodi's video player
+func (k *Kodi) Send(ctx context.Context, msg Message) (Result, error) {
+	params := map[string]any{"item": map[string]string{"file": msg.StreamURL}}
+	if err := k.call(ctx, "Player.Open", params, nil); err != nil {
+		return Dropped, err
+	}
+	k.sent.remember(msg.StreamURL, msg.NowPlaying.Title)
+	return Delivered, nil
+}
+
+// Control stops playback, toggles pause or sets the volume
+func (k *Kodi) Control(ctx context.Context, action Action, value int) error {
+	if action == Volume {
+		return k.call(ctx, "Application.SetVolume", map[string]int{"volume": value}, nil)
+	}
+
+	var method string
+	switch action {
+	case Stop:
+		method = "Player.Stop"
+	case Pause:
+		method = "Player.PlayPause"
+	default:
+		return ErrUnsupported
+	}
+	playerID, ok, err := k.activePlayer(ctx)
+	if err != nil || !ok {
+		return err
+	}
+	return k.call(ctx, method, map[string]int{"playerid": playerID}, nil)
+}
+
+// State reads the playing item, pause state and volume from Kodi
+func (k *Kodi) State(ctx context.Context) (PlayerState, error) {
+	state := PlayerState{Volume: -1}
+	var app struct {
+		Volume int `json:"volume"`
+	}
+	if err := k.call(ctx, "Application.GetProperties", map[string][]string{"properties": {"volume"}}, &app); err != nil {
+		return state, err
+	}
+	state.Volume = app.Volume
+
+	playerID, ok, err := k.activePlayer(ctx)
+	if err != nil || !ok {
+		return state, err
+	}
+	var item struct {
+		Item struct {
+			Label string `json:"label"`
+			Title string `json:"title"`
+			File  string `json:"file"`
+		} `json:"item"`
+	}
+	params := map[string]any{"playerid": playerID, "properties": []string{"title", "file"}}
+	if err := k.call(ctx, "Player.GetItem", params, &item); err != nil {
+		return state, err
+	}
+	var props struct {
+		Speed int `json:"speed"`
+	}
+	params = map[string]any{"playerid": playerID, "properties": []string{"speed"}}
+	if err := k.call(ctx, "Player.GetProperties", params, &props); err != nil {
+		return state, err
+	}
+
+	title := item.Item.Title
+	if title == "" {
+		title = item.Item.Label
+	}
+	state.Playing = true
+	state.Path = item.Item.File
+	state.Title = k.sent.titleFor(item.Item.File, title)
+	state.Paused = props.Speed == 0
+	return state, nil
+}
+
+// activePlayer returns the ID of the player that is playing, if any
+func (k *Kodi) activePlayer(ctx context.Context) (int, bool, error) {
+	var players []struct {
+		PlayerID int    `json:"playerid"`
+		Type     string `json:"type"`
+	}
+	if err := k.call(ctx, "Player.GetActivePlayers", nil, &players); err != nil {
+		return 0, false, err
+	}
+	for _, p := range players {
+		if p.Type == "video" {
+			return p.PlayerID, true, nil
+		}
+	}
+	if len(players) > 0 {
+		return players[0].PlayerID, true, nil
+	}
+	return 0, false, nil
+}
+
+type kodiRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+	ID      uint64 `json:"id"`
+}
+
+type kodiResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// call invokes a JSON-RPC method and decodes its result into result, if given
+func (k *Kodi) call(ctx context.Context, method string, params, result any) error {
+	var header http.Header
+	if k.username != "" || k.password != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(k.username + ":" + k.password))
+		header = http.Header{"Authorization": {"Basic " + credentials}}
+	}
+	payload := kodiRequest{JSONRPC: "2.0", Method: method, Params: params, ID: k.nextID.Add(1)}
+	body, err := sendJSON(ctx, k.client, http.MethodPost, k.url, header, payload)
+	if err != nil {
+		return fmt.Errorf("Kodi %s failed: %w", method, err)
+	}
+
+	var resp kodiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("Failed to decode Kodi response: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("Kodi %s failed: %s (%d)", method, resp.Error.Message, resp.Error.Code)
+	}
+	if result != nil {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("Failed to decode Kodi %s result: %w", method, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// kodiServer answers JSON-RPC calls with the result listed for their method
+func kodiServer(t *testing.T, results map[string]string) *recorder {
+	return newRecorder(t, func(w http.ResponseWriter, r *http.Request) {
+		var req kodiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		result, ok := results[req.Method]
+		if !ok {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"Method not found."}}`, req.ID)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, result)
+	})
+}
+
+func TestKodiSend(t *testing.T) {
+	srv := kodiServer(t, map[string]string{"Player.Open": `"OK"`})
+	k := NewKodi(config.SinkConfig{Name: "kodi", URL: srv.URL + "/", Username: "kodi", Password: "secret"}, testClient())
+
+	result, err := k.Send(context.Background(), testMessage)
+	if err != nil || result != Delivered {
+		t.Fatalf("Send = %v, %v", result, err)
+	}
+	req := srv.only(t)
+	if req.Method != http.MethodPost || req.Path != "/jsonrpc" {
+		t.Errorf("request = %s %s, want POST /jsonrpc", req.Method, req.Path)
+	}
+	if user, pass, ok := (&http.Request{Header: req.Header}).BasicAuth(); !ok || user != "kodi" || pass != "secret" {
+		t.Errorf("basic auth = %q, %q, %v", user, pass, ok)
+	}
+	var got struct {
+		JSONRPC string `json:"jsonrpc"`
+		Method  string `json:"method"`
+		Params  struct {
+			Item struct {
+				File string `json:"file"`
+			} `json:"item"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(req.Body, &got); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if got.JSONRPC != "2.0" || got.Method != "Player.Open" || got.Params.Item.File != testMessage.StreamURL {
+		t.Errorf("call = %+v", got)
+	}
+}
+
+func TestKodiWithoutCredentials(t *testing.T) {
+	srv := kodiServer(t, map[string]string{"Player.Open": `"OK"`})
+	k := NewKodi(config.SinkConfig{Name: "kodi", URL: srv.URL + "/jsonrpc"}, testClient())
+
+	if _, err := k.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	req := srv.only(t)
+	if req.Path != "/jsonrpc" {
+		t.Errorf("path = %s, want /jsonrpc", req.Path)
+	}
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		t.Errorf("Authorization = %q, want none", auth)
+	}
+}
+
+func TestKodiState(t *testing.T) {
+	srv := kodiServer(t, map[string]string{
+		"Player.Open":               `"OK"`,
+		"Application.GetProperties": `{"volume":40}`,
+		"Player.GetActivePlayers":   `[{"playerid":0,"type":"audio"},{"playerid":1,"type":"video"}]`,
+		"Player.GetItem":            `{"item":{"label":"1.ts","title":"","file":"` + testMessage.StreamURL + `"}}`,
+		"Player.GetProperties":      `{"speed":0}`,
+	})
+	k := NewKodi(config.SinkConfig{Name: "kodi", URL: srv.URL}, testClient())
+	if _, err := k.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := k.State(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PlayerState{Playing: true, Title: "BBC One", Path: testMessage.StreamURL, Paused: true, Volume: 40}
+	if state != want {
+		t.Errorf("state = %+v, want %+v", state, want)
+	}
+}
+
+func TestKodiErrors(t *testing.T) {
+	srv := kodiServer(t, nil)
+	k := NewKodi(config.SinkConfig{Name: "kodi", URL: srv.URL}, testClient())
+	result, err := k.Send(context.Background(), testMessage)
+	if result != Dropped || err == nil || err.Error() != "Kodi Player.Open failed: Method not found. (-32601)" {
+		t.Errorf("Send = %v, %v", result, err)
+	}
+
+	srv = newRecorder(t, respond(http.StatusUnauthorized, "text/html", "<html>401</html>"))
+	k = NewKodi(config.SinkConfig{Name: "kodi", URL: srv.URL, Password: "wrong"}, testClient())
+	result, err = k.Send(context.Background(), testMessage)
+	if result != Dropped || err == nil || err.Error() != "Kodi Player.Open failed: Request failed with status code 401" {
+		t.Errorf("Send = %v, %v", result, err)
+	}
+
+	if err := k.Control(context.Background(), Action("rewind"), 0); err != ErrUnsupported {
+		t.Errorf("Control(rewind) = %v, want ErrUnsupported", err)
+	}
+	if err := NewKodi(config.SinkConfig{Name: "kodi", URL: closedURL()}, testClient()).Control(context.Background(), Volume, 50); err == nil || !strings.Contains(err.Error(), "Application.SetVolume") {
+		t.Errorf("Control against a closed server = %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// Matrix sends a text message to a room through the client-server API, as the
+// user owning the access token. The command is the plain body so bots can parse
+// it; clients show the stream details from the formatted body.
+type Matrix struct {
+	name       string
+	homeserver string
+	token      string
+	room       string
+	prefix     string
+	client     *http.Client
+	txn        atomic.Uint64
+}
+
+// matrixMessage is an m.room.message event with an HTML formatted body
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+var _ Sink = (*Matrix)(nil)
+
+// NewMatrix creates a Matrix room sink
+func NewMatrix(cfg config.SinkConfig, client *http.Client) *Matrix {
+	return &Matrix{
+		name:       cfg.Name,
+		homeserver: strings.TrimSuffix(cfg.URL, "/"),
+		token:      cfg.Token,
+		room:       cfg.Room,
+		prefix:     cfg.Prefix,
+		client:     client,
+	}
+}
+
+// Name returns the sink's name
+func (m *Matrix) Name() string {
+	return m.name
+}
+
+// Send posts the command to the room
+func (m *Matrix) Send(ctx context.Context, msg Message) (Result, error) {
+	command := Command(m.prefix, msg.StreamURL)
+	payload := matrixMessage{MsgType: "m.text", Body: command}
+	if details := msg.NowPlaying.Details(); len(details) > 0 {
+		lines := make([]string, 0, len(details)+1)
+		lines = append(lines, "<code>"+html.EscapeString(command)+"</code>", "<strong>"+html.EscapeString(details[0])+"</strong>")
+		for _, line := range details[1:] {
+			lines = append(lines, html.EscapeString(line))
+		}
+		payload.Format = "org.matrix.custom.html"
+		payload.FormattedBody = strings.Join(lines, "<br>")
+	}
+
+	// The transaction ID makes the homeserver ignore a retried duplicate
+	txnID := fmt.Sprintf("gmc-%d-%d", time.Now().UnixNano(), m.txn.Add(1))
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", m.homeserver, url.PathEscape(m.room), txnID)
+	header := http.Header{"Authorization": {"Bearer " + m.token}}
+	_, err := sendJSON(ctx, m.client, http.MethodPut, endpoint, header, payload)
+	return result(err)
+}
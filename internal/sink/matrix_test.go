@@ -0,0 +1,66 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+func TestMatrixSend(t *testing.T) {
+	srv := newRecorder(t, respond(http.StatusOK, "application/json", `{"event_id":"$1"}`))
+	m := NewMatrix(config.SinkConfig{Name: "matrix", URL: srv.URL + "/", Token: "syt_secret", Room: "!room:example.org", Prefix: "!play"}, testClient())
+
+	msg := testMessage
+	msg.NowPlaying.Title = "Tom & Jerry <HD>"
+	result, err := m.Send(context.Background(), msg)
+	if err != nil || result != Delivered {
+		t.Fatalf("Send = %v, %v", result, err)
+	}
+	req := srv.only(t)
+	prefix := "/_matrix/client/v3/rooms/!room:example.org/send/m.room.message/"
+	if req.Method != http.MethodPut || !strings.HasPrefix(req.Path, prefix) || len(req.Path) == len(prefix) {
+		t.Errorf("request = %s %s, want PUT %s<txn>", req.Method, req.Path, prefix)
+	}
+	if auth := req.Header.Get("Authorization"); auth != "Bearer syt_secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	var got matrixMessage
+	if err := json.Unmarshal(req.Body, &got); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if got.MsgType != "m.text" || got.Body != "!play "+msg.StreamURL || got.Format != "org.matrix.custom.html" {
+		t.Errorf("message = %+v", got)
+	}
+	want := "<code>!play " + msg.StreamURL + "</code><br><strong>Tom &amp; Jerry &lt;HD&gt;</strong><br>Sent by alice"
+	if got.FormattedBody != want {
+		t.Errorf("formatted body = %q, want %q", got.FormattedBody, want)
+	}
+}
+
+func TestMatrixTransactionIDs(t *testing.T) {
+	srv := newRecorder(t, respond(http.StatusOK, "application/json", `{}`))
+	m := NewMatrix(config.SinkConfig{Name: "matrix", URL: srv.URL, Token: "t", Room: "!room:example.org"}, testClient())
+
+	for range 2 {
+		if _, err := m.Send(context.Background(), testMessage); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if srv.requests[0].Path == srv.requests[1].Path {
+		t.Errorf("both sends used transaction %s", srv.requests[0].Path)
+	}
+}
+
+func TestMatrixRejected(t *testing.T) {
+	srv := newRecorder(t, respond(http.StatusForbidden, "application/json", `{"errcode":"M_FORBIDDEN","error":"Not in room"}`))
+	m := NewMatrix(config.SinkConfig{Name: "matrix", URL: srv.URL, Token: "t", Room: "!room:example.org"}, testClient())
+
+	result, err := m.Send(context.Background(), testMessage)
+	if result != Dropped || err == nil || !strings.Contains(err.Error(), "M_FORBIDDEN") {
+		t.Errorf("Send = %v, %v", result, err)
+	}
+}
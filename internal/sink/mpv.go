@@ -0,0 +1,181 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// Mpv drives an mpv instance through its JSON IPC, started with
+// --input-ipc-server. The socket is usually a unix socket; a TCP address works
+// when it is forwarded, e.g. with socat.
+type Mpv struct {
+	name    string
+	network string // "unix" or "tcp"
+	address string
+	dialer  net.Dialer
+	sent    sentTitles
+}
+
+// mpvTimeout bounds an IPC exchange when the caller sets no deadline
+const mpvTimeout = 5 * time.Second
+
+var _ Player = (*Mpv)(nil)
+
+// NewMpv creates an mpv sink. The address is a socket path, optionally written
+// as unix:///path, or tcp://host:port.
+func NewMpv(cfg config.SinkConfig) *Mpv {
+	m := &Mpv{name: cfg.Name, network: "unix", address: strings.TrimPrefix(cfg.URL, "unix://")}
+	if address, ok := strings.CutPrefix(cfg.URL, "tcp://"); ok {
+		m.network, m.address = "tcp", address
+	}
+	return m
+}
+
+// Name returns the sink's name
+func (m *Mpv) Name() string {
+	return m.name
+}
+
+// Send replaces whatever mpv is playing with the stream
+func (m *Mpv) Send(ctx context.Context, msg Message) (Result, error) {
+	conn, err := m.dial(ctx)
+	if err != nil {
+		return Dropped, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.call("loadfile", msg.StreamURL, "replace"); err != nil {
+		return Dropped, err
+	}
+	m.sent.remember(msg.StreamURL, msg.NowPlaying.Title)
+	return Delivered, nil
+}
+
+// Control stops playback, toggles pause or sets the volume
+func (m *Mpv) Control(ctx context.Context, action Action, value int) error {
+	var args []any
+	switch action {
+	case Stop:
+		args = []any{"stop"}
+	case Pause:
+		args = []any{"cycle", "pause"}
+	case Volume:
+		args = []any{"set_property", "volume", value}
+	default:
+		return ErrUnsupported
+	}
+
+	conn, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.call(args...)
+	return err
+}
+
+// State reads the loaded file, title, pause state and volume from mpv
+func (m *Mpv) State(ctx context.Context) (PlayerState, error) {
+	state := PlayerState{Volume: -1}
+	conn, err := m.dial(ctx)
+	if err != nil {
+		return state, err
+	}
+	defer conn.Close()
+
+	var volume float64
+	if err := conn.property("volume", &volume); err == nil {
+		state.Volume = int(volume + 0.5)
+	}
+	var idle bool
+	if err := conn.property("idle-active", &idle); err != nil {
+		return state, err
+	}
+	if idle {
+		return state, nil
+	}
+
+	state.Playing = true
+	// Unavailable properties, such as the title while a stream opens, stay empty
+	conn.property("path", &state.Path)
+	conn.property("pause", &state.Paused)
+	var title string
+	conn.property("media-title", &title)
+	state.Title = m.sent.titleFor(state.Path, title)
+	return state, nil
+}
+
+// mpvConn is one IPC connection. Requests on it are answered in order, with
+// events from mpv interleaved.
+type mpvConn struct {
+	net.Conn
+	dec    *json.Decoder
+	nextID int
+}
+
+type mpvRequest struct {
+	Command   []any `json:"command"`
+	RequestID int   `json:"request_id"`
+}
+
+type mpvResponse struct {
+	Event     string          `json:"event"` // Set on unsolicited events
+	RequestID int             `json:"request_id"`
+	Error     string          `json:"error"` // "success" unless the command failed
+	Data      json.RawMessage `json:"data"`
+}
+
+func (m *Mpv) dial(ctx context.Context) (*mpvConn, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, mpvTimeout)
+		defer cancel()
+	}
+	conn, err := m.dialer.DialContext(ctx, m.network, m.address)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to mpv: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	return &mpvConn{Conn: conn, dec: json.NewDecoder(conn)}, nil
+}
+
+// call runs a command and returns its data
+func (c *mpvConn) call(args ...any) (json.RawMessage, error) {
+	c.nextID++
+	if err := json.NewEncoder(c.Conn).Encode(mpvRequest{Command: args, RequestID: c.nextID}); err != nil {
+		return nil, fmt.Errorf("Failed to send mpv command: %w", err)
+	}
+	for {
+		var resp mpvResponse
+		if err := c.dec.Decode(&resp); err != nil {
+			return nil, fmt.Errorf("Failed to read mpv response: %w", err)
+		}
+		if resp.Event != "" || resp.RequestID != c.nextID {
+			continue
+		}
+		if resp.Error != "success" {
+			return nil, fmt.Errorf("mpv %s failed: %s", args[0], resp.Error)
+		}
+		return resp.Data, nil
+	}
+}
+
+// property reads a property into v
+func (c *mpvConn) property(name string, v any) error {
+	data, err := c.call("get_property", name)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return errors.New("mpv returned no data for " + name)
+	}
+	return json.Unmarshal(data, v)
+}
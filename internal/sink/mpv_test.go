@@ -0,0 +1,193 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// mpvServer stands in for mpv's IPC socket. It answers get_property from
+// properties, fails commands listed in failing, and succeeds otherwise. Before
+// each reply it sends an event and a reply to another request ID, as mpv does
+// when events fire or other clients share the socket.
+type mpvServer struct {
+	path       string
+	properties map[string]string // JSON values
+	failing    map[string]string // Command name to mpv error
+	mu         sync.Mutex
+	commands   []string // JSON of each command received
+}
+
+func newMpvServer(t *testing.T, properties, failing map[string]string) *mpvServer {
+	t.Helper()
+	s := &mpvServer{path: t.TempDir() + "/mpv.sock", properties: properties, failing: failing}
+	ln, err := net.Listen("unix", s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *mpvServer) serve(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	for {
+		var req struct {
+			Command   []json.RawMessage `json:"command"`
+			RequestID int               `json:"request_id"`
+		}
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		command, _ := json.Marshal(req.Command)
+		s.mu.Lock()
+		s.commands = append(s.commands, string(command))
+		s.mu.Unlock()
+
+		var name, arg string
+		json.Unmarshal(req.Command[0], &name)
+		if len(req.Command) > 1 {
+			json.Unmarshal(req.Command[1], &arg)
+		}
+		result, data := "success", "null"
+		switch value, ok := s.properties[arg]; {
+		case s.failing[name] != "":
+			result = s.failing[name]
+		case name == "get_property" && ok:
+			data = value
+		case name == "get_property":
+			result = "property unavailable"
+		}
+
+		fmt.Fprintf(conn, `{"event":"property-change","id":1,"name":"volume","data":0}`+"\n")
+		fmt.Fprintf(conn, `{"request_id":%d,"error":"success","data":"not for us"}`+"\n", req.RequestID+100)
+		fmt.Fprintf(conn, `{"request_id":%d,"error":%q,"data":%s}`+"\n", req.RequestID, result, data)
+	}
+}
+
+func (s *mpvServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.commands)
+}
+
+func TestMpvSend(t *testing.T) {
+	srv := newMpvServer(t, nil, nil)
+	m := NewMpv(config.SinkConfig{Name: "mpv", URL: "unix://" + srv.path})
+
+	result, err := m.Send(context.Background(), testMessage)
+	if err != nil || result != Delivered {
+		t.Fatalf("Send = %v, %v", result, err)
+	}
+	want := []string{`["loadfile","` + testMessage.StreamURL + `","replace"]`}
+	if got := srv.received(); !slices.Equal(got, want) {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+}
+
+func TestMpvControl(t *testing.T) {
+	tests := []struct {
+		action Action
+		value  int
+		want   string
+	}{
+		{Stop, 0, `["stop"]`},
+		{Pause, 0, `["cycle","pause"]`},
+		{Volume, 35, `["set_property","volume",35]`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.action), func(t *testing.T) {
+			srv := newMpvServer(t, nil, nil)
+			if err := NewMpv(config.SinkConfig{Name: "mpv", URL: srv.path}).Control(context.Background(), tt.action, tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if got := srv.received(); !slices.Equal(got, []string{tt.want}) {
+				t.Errorf("commands = %v, want [%s]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMpvState(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties map[string]string
+		want       PlayerState
+	}{
+		{
+			name: "playing",
+			properties: map[string]string{
+				"volume":      "72.6",
+				"idle-active": "false",
+				"path":        `"` + testMessage.StreamURL + `"`,
+				"pause":       "true",
+				"media-title": `"1.ts"`,
+			},
+			want: PlayerState{Playing: true, Title: "BBC One", Path: testMessage.StreamURL, Paused: true, Volume: 73},
+		},
+		{
+			name:       "idle",
+			properties: map[string]string{"volume": "100", "idle-active": "true"},
+			want:       PlayerState{Volume: 100},
+		},
+		{
+			name:       "started elsewhere without a title",
+			properties: map[string]string{"idle-active": "false", "path": `"http://other.example/2.ts"`},
+			want:       PlayerState{Playing: true, Path: "http://other.example/2.ts", Volume: -1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newMpvServer(t, tt.properties, nil)
+			m := NewMpv(config.SinkConfig{Name: "mpv", URL: srv.path})
+			if _, err := m.Send(context.Background(), testMessage); err != nil {
+				t.Fatal(err)
+			}
+
+			state, err := m.State(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state != tt.want {
+				t.Errorf("state = %+v, want %+v", state, tt.want)
+			}
+		})
+	}
+}
+
+func TestMpvErrors(t *testing.T) {
+	srv := newMpvServer(t, nil, map[string]string{"loadfile": "error running command"})
+	m := NewMpv(config.SinkConfig{Name: "mpv", URL: srv.path})
+	result, err := m.Send(context.Background(), testMessage)
+	if result != Dropped || err == nil || err.Error() != "mpv loadfile failed: error running command" {
+		t.Errorf("Send = %v, %v", result, err)
+	}
+
+	if _, err := m.State(context.Background()); err == nil {
+		t.Error("State without idle-active succeeded")
+	}
+	if err := m.Control(context.Background(), Action("rewind"), 0); err != ErrUnsupported {
+		t.Errorf("Control(rewind) = %v, want ErrUnsupported", err)
+	}
+
+	missing := NewMpv(config.SinkConfig{Name: "mpv", URL: t.TempDir() + "/missing.sock"})
+	if result, err := missing.Send(context.Background(), testMessage); result != Dropped || err == nil {
+		t.Errorf("Send to a missing socket = %v, %v", result, err)
+	}
+}
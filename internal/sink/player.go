@@ -0,0 +1,62 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Player is a sink that drives a media player directly, so besides playing
+// streams it can be controlled and report what it is playing
+type Player interface {
+	Sink
+	// Control applies an action; value is the volume in percent for Volume
+	Control(ctx context.Context, action Action, value int) error
+	// State reads back what the player is doing
+	State(ctx context.Context) (PlayerState, error)
+}
+
+// Action is a playback control
+type Action string
+
+const (
+	Stop   Action = "stop"
+	Pause  Action = "pause" // Toggles between paused and playing
+	Volume Action = "volume"
+)
+
+// ErrUnsupported is returned for actions a player cannot perform
+var ErrUnsupported = errors.New("Not supported by this player")
+
+// PlayerState is what a player reports it is doing
+type PlayerState struct {
+	Playing bool   `json:"playing"` // False when the player is idle
+	Title   string `json:"title,omitempty"`
+	Path    string `json:"path,omitempty"` // Loaded file or stream URL
+	Paused  bool   `json:"paused"`
+	Volume  int    `json:"volume"` // Percent, or -1 when unknown
+}
+
+// sentTitles remembers the titles of streams a player was sent, as players
+// usually only know a live stream by its URL
+type sentTitles struct {
+	mu    sync.Mutex
+	url   string
+	title string
+}
+
+func (s *sentTitles) remember(url, title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.url, s.title = url, title
+}
+
+// titleFor returns the sent title for url, or fallback if it was not sent from here
+func (s *sentTitles) titleFor(url, fallback string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if url == s.url && s.title != "" {
+		return s.title
+	}
+	return fallback
+}
@@ -0,0 +1,83 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// sendJSON sends a JSON payload and returns the response body, treating any
+// status other than 2xx as a failure
+func sendJSON(ctx context.Context, client *http.Client, method, url string, header http.Header, payload any) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal payload: %w", err)
+	}
+	return send(ctx, client, method, url, header, "application/json", body)
+}
+
+// send issues a request and returns the response body, treating any status
+// other than 2xx as a failure
+func send(ctx context.Context, client *http.Client, method, endpoint string, header http.Header, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create request: %w", WithoutURL(err))
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to send request: %w", WithoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Services explain rejections in the body, e.g. a bad token or unknown room;
+		// HTML error pages are left out
+		contentType := resp.Header.Get("Content-Type")
+		detail := strings.TrimSpace(string(respBody))
+		if detail != "" && (strings.Contains(contentType, "json") || strings.HasPrefix(contentType, "text/plain")) {
+			return nil, fmt.Errorf("Request failed with status code %d: %s", resp.StatusCode, Truncate(detail, 200))
+		}
+		return nil, fmt.Errorf("Request failed with status code %d", resp.StatusCode)
+	}
+	return respBody, nil
+}
+
+// WithoutURL drops the request URL from an error. URLs can hold secrets, such
+// as a webhook's key or, in a VLC command, the stream URL with the provider's
+// credentials.
+func WithoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// result converts a delivery error to the Result reported by Send
+func result(err error) (Result, error) {
+	if err != nil {
+		return Dropped, err
+	}
+	return Delivered, nil
+}
+
+// Truncate shortens s to at most n runes, ending it with an ellipsis when cut
+func Truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,129 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// received is a request seen by a test server
+type received struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// recorder is a test server that records each request before handing it to
+// handler
+type recorder struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []received
+}
+
+func newRecorder(t *testing.T, handler http.HandlerFunc) *recorder {
+	t.Helper()
+	rec := &recorder{}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		rec.mu.Lock()
+		rec.requests = append(rec.requests, received{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+			Header: r.Header.Clone(),
+			Body:   body,
+		})
+		rec.mu.Unlock()
+		handler(w, r)
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+// respond answers every request with a fixed status, content type and body
+func respond(status int, contentType, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}
+}
+
+// only returns the single request the server received
+func (rec *recorder) only(t *testing.T) received {
+	t.Helper()
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.requests) != 1 {
+		t.Fatalf("server received %d requests, want 1", len(rec.requests))
+	}
+	return rec.requests[0]
+}
+
+// closedURL returns the URL of a server that is no longer listening
+func closedURL() string {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+func testClient() *http.Client {
+	return &http.Client{Timeout: 5 * time.Second}
+}
+
+func TestSendErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		want        string // Error message, or empty for success
+	}{
+		{name: "success", status: http.StatusOK, contentType: "text/plain", body: "ok"},
+		{name: "no content", status: http.StatusNoContent},
+		{name: "json detail", status: http.StatusForbidden, contentType: "application/json", body: `{"error":"bad token"}`,
+			want: `Request failed with status code 403: {"error":"bad token"}`},
+		{name: "plain text detail", status: http.StatusBadRequest, contentType: "text/plain; charset=utf-8", body: "invalid_payload\n",
+			want: "Request failed with status code 400: invalid_payload"},
+		{name: "html page left out", status: http.StatusBadGateway, contentType: "text/html", body: "<html>Bad gateway</html>",
+			want: "Request failed with status code 502"},
+		{name: "long detail truncated", status: http.StatusInternalServerError, contentType: "text/plain", body: strings.Repeat("x", 300),
+			want: "Request failed with status code 500: " + strings.Repeat("x", 199) + "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newRecorder(t, respond(tt.status, tt.contentType, tt.body))
+			_, err := send(context.Background(), testClient(), http.MethodPost, srv.URL, nil, "application/json", []byte("{}"))
+			switch {
+			case tt.want == "" && err != nil:
+				t.Fatalf("send failed: %v", err)
+			case tt.want != "" && (err == nil || err.Error() != tt.want):
+				t.Fatalf("send error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendKeepsURLOutOfErrors(t *testing.T) {
+	endpoint := closedURL() + "/hooks/secret-key?password=hunter2"
+	_, err := send(context.Background(), testClient(), http.MethodPost, endpoint, nil, "application/json", nil)
+	if err == nil {
+		t.Fatal("expected an error from a closed server")
+	}
+	if strings.Contains(err.Error(), "secret-key") || strings.Contains(err.Error(), "hunter2") {
+		t.Fatalf("error leaks the URL: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+// go-media-control/internal/sink/sink.go
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// Sink is somewhere a stream can be sent, such as a chat room a media bot
+// listens in
+type Sink interface {
+	// Name identifies the sink in the target picker and in SendRequest.Target
+	Name() string
+	// Send delivers a stream, reporting whether it arrived or is still queued
+	Send(ctx context.Context, msg Message) (Result, error)
+}
+
+// Result reports what happened to a message handed to a Sink
+type Result string
+
+const (
+	Delivered Result = "delivered" // Accepted by the service
+	Queued    Result = "queued"    // Waiting on a rate limit or retry; it will still be sent in order
+	Dropped   Result = "dropped"   // Rejected by the service, out of retries or the queue was full
+)
+
+// Message is a stream being sent
+type Message struct {
+	StreamURL  string
+	NowPlaying NowPlaying
+}
+
+// Programme is a guide entry sent with a stream
+type Programme struct {
+	Title string    `json:"title"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// NowPlaying describes a sent stream. Empty details are left out of messages.
+type NowPlaying struct {
+	Title    string     `json:"title"` // Channel, movie or episode name
+	Logo     string     `json:"logo,omitempty"`
+	Type     string     `json:"type"` // live, movie, series or timeshift
+	Provider string     `json:"provider,omitempty"`
+	Current  *Programme `json:"current,omitempty"`
+	Next     *Programme `json:"next,omitempty"`
+	Replay   bool       `json:"replay,omitempty"` // Current is a past programme sent for catch-up
+	User     string     `json:"user,omitempty"`   // Who sent the stream
+}
+
+// Details lists the stream's title, guide and sender as lines of plain text
+func (np NowPlaying) Details() []string {
+	var lines []string
+	if np.Title != "" {
+		lines = append(lines, np.Title)
+	}
+	if np.Current != nil {
+		name := "Now"
+		if np.Replay {
+			name = "Replay"
+		}
+		lines = append(lines, np.Current.line(name))
+	}
+	if np.Next != nil {
+		lines = append(lines, np.Next.line("Next"))
+	}
+	if np.User != "" {
+		lines = append(lines, "Sent by "+np.User)
+	}
+	return lines
+}
+
+func (p *Programme) line(name string) string {
+	return fmt.Sprintf("%s: %s (%s–%s)", name, p.Title, p.Start.Local().Format("15:04"), p.End.Local().Format("15:04"))
+}
+
+// Command joins a bot's command prefix and the stream URL
+func Command(prefix, streamURL string) string {
+	return strings.TrimSpace(prefix + " " + streamURL)
+}
+
+// New creates a sink from its configuration
+func New(cfg config.SinkConfig) (Sink, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	switch cfg.Type {
+	case config.SinkWebhook:
+		return NewWebhook(cfg, client)
+	case config.SinkSlack:
+		return NewSlack(cfg, client), nil
+	case config.SinkMatrix:
+		return NewMatrix(cfg, client), nil
+	case config.SinkTelegram:
+		return NewTelegram(cfg, client), nil
+	case config.SinkMpv:
+		return NewMpv(cfg), nil
+	case config.SinkKodi:
+		return NewKodi(cfg, client), nil
+	case config.SinkVLC:
+		return NewVLC(cfg, client), nil
+	default:
+		return nil, fmt.Errorf("Unknown sink type %q", cfg.Type)
+	}
+}
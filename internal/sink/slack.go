@@ -0,0 +1,74 @@
+package sink
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// Slack posts to a Slack incoming webhook. The command is the message text so
+// bots can parse it, with the stream details in an attachment below.
+type Slack struct {
+	name     string
+	url      string
+	prefix   string
+	username string // Optional override of the webhook's name, where the workspace allows it
+	iconURL  string
+	client   *http.Client
+}
+
+// slackMessage is an incoming webhook payload
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Username    string            `json:"username,omitempty"`
+	IconURL     string            `json:"icon_url,omitempty"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Title    string `json:"title,omitempty"`
+	Text     string `json:"text,omitempty"`
+	ThumbURL string `json:"thumb_url,omitempty"`
+	Fallback string `json:"fallback"`
+}
+
+var _ Sink = (*Slack)(nil)
+
+// NewSlack creates a Slack incoming webhook sink
+func NewSlack(cfg config.SinkConfig, client *http.Client) *Slack {
+	return &Slack{
+		name:     cfg.Name,
+		url:      cfg.URL,
+		prefix:   cfg.Prefix,
+		username: cfg.Username,
+		iconURL:  cfg.AvatarURL,
+		client:   client,
+	}
+}
+
+// Name returns the sink's name
+func (s *Slack) Name() string {
+	return s.name
+}
+
+// Send posts the command and stream details to the webhook
+func (s *Slack) Send(ctx context.Context, msg Message) (Result, error) {
+	payload := slackMessage{
+		Text:     Command(s.prefix, msg.StreamURL),
+		Username: s.username,
+		IconURL:  s.iconURL,
+	}
+	if details := msg.NowPlaying.Details(); len(details) > 0 {
+		payload.Attachments = []slackAttachment{{
+			Title:    details[0],
+			Text:     strings.Join(details[1:], "\n"),
+			ThumbURL: msg.NowPlaying.Logo,
+			Fallback: strings.Join(details, " - "),
+		}}
+	}
+	// Slack answers incoming webhooks with a plain "ok"
+	_, err := sendJSON(ctx, s.client, http.MethodPost, s.url, nil, payload)
+	return result(err)
+}
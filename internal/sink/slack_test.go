@@ -0,0 +1,64 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+func TestSlackSend(t *testing.T) {
+	srv := newRecorder(t, respond(http.StatusOK, "text/html", "ok"))
+	s := NewSlack(config.SinkConfig{Name: "slack", URL: srv.URL + "/services/T/B/X", Prefix: "!play", Username: "Media", AvatarURL: "http://icon.example/i.png"}, testClient())
+
+	result, err := s.Send(context.Background(), testMessage)
+	if err != nil || result != Delivered {
+		t.Fatalf("Send = %v, %v", result, err)
+	}
+	req := srv.only(t)
+	if req.Method != http.MethodPost || req.Path != "/services/T/B/X" {
+		t.Errorf("request = %s %s", req.Method, req.Path)
+	}
+	var got slackMessage
+	if err := json.Unmarshal(req.Body, &got); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if got.Text != "!play "+testMessage.StreamURL || got.Username != "Media" || got.IconURL != "http://icon.example/i.png" {
+		t.Errorf("message = %+v", got)
+	}
+	if len(got.Attachments) != 1 {
+		t.Fatalf("attachments = %+v", got.Attachments)
+	}
+	a := got.Attachments[0]
+	if a.Title != "BBC One" || a.Text != "Sent by alice" || a.ThumbURL != testMessage.NowPlaying.Logo || a.Fallback != "BBC One - Sent by alice" {
+		t.Errorf("attachment = %+v", a)
+	}
+}
+
+func TestSlackWithoutDetails(t *testing.T) {
+	srv := newRecorder(t, respond(http.StatusOK, "text/html", "ok"))
+	s := NewSlack(config.SinkConfig{Name: "slack", URL: srv.URL}, testClient())
+
+	if _, err := s.Send(context.Background(), Message{StreamURL: "http://example/1.ts"}); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	json.Unmarshal(srv.only(t).Body, &got)
+	for _, field := range []string{"attachments", "username", "icon_url"} {
+		if _, ok := got[field]; ok {
+			t.Errorf("empty %s sent: %v", field, got)
+		}
+	}
+}
+
+func TestSlackRejected(t *testing.T) {
+	srv := newRecorder(t, respond(http.StatusForbidden, "text/plain", "invalid_token"))
+	s := NewSlack(config.SinkConfig{Name: "slack", URL: srv.URL}, testClient())
+
+	result, err := s.Send(context.Background(), testMessage)
+	if result != Dropped || err == nil || err.Error() != "Request failed with status code 403: invalid_token" {
+		t.Errorf("Send = %v, %v", result, err)
+	}
+}
@@ -0,0 +1,72 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// Telegram sends a message to a chat through the Bot API's sendMessage
+type Telegram struct {
+	name   string
+	apiURL string // Bot API base URL, https://api.telegram.org unless self-hosted
+	token  string
+	chatID string
+	prefix string
+	client *http.Client
+}
+
+// telegramMessage is a sendMessage request
+type telegramMessage struct {
+	ChatID                string `json:"chat_id"` // Numeric ID or @channelusername
+	Text                  string `json:"text"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+var _ Sink = (*Telegram)(nil)
+
+// NewTelegram creates a Telegram chat sink
+func NewTelegram(cfg config.SinkConfig, client *http.Client) *Telegram {
+	return &Telegram{
+		name:   cfg.Name,
+		apiURL: strings.TrimSuffix(cfg.URL, "/"),
+		token:  cfg.Token,
+		chatID: cfg.ChatID,
+		prefix: cfg.Prefix,
+		client: client,
+	}
+}
+
+// Name returns the sink's name
+func (t *Telegram) Name() string {
+	return t.name
+}
+
+// Send posts the command, followed by the stream details, to the chat
+func (t *Telegram) Send(ctx context.Context, msg Message) (Result, error) {
+	lines := append([]string{Command(t.prefix, msg.StreamURL)}, msg.NowPlaying.Details()...)
+	payload := telegramMessage{
+		ChatID:                t.chatID,
+		Text:                  strings.Join(lines, "\n"),
+		DisableWebPagePreview: true,
+	}
+	body, err := sendJSON(ctx, t.client, http.MethodPost, fmt.Sprintf("%s/bot%s/sendMessage", t.apiURL, t.token), nil, payload)
+	if err != nil {
+		// The token is part of the URL, so keep it out of logged errors
+		return Dropped, errors.New(strings.ReplaceAll(err.Error(), t.token, "<token>"))
+	}
+
+	var resp struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || !resp.OK {
+		return Dropped, fmt.Errorf("Telegram rejected the message: %s", resp.Description)
+	}
+	return Delivered, nil
+}
@@ -0,0 +1,68 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+const testBotToken = "123456:bot-secret"
+
+func TestTelegramSend(t *testing.T) {
+	srv := newRecorder(t, respond(http.StatusOK, "application/json", `{"ok":true,"result":{}}`))
+	tg := NewTelegram(config.SinkConfig{Name: "telegram", URL: srv.URL, Token: testBotToken, ChatID: "@media", Prefix: "/play"}, testClient())
+
+	result, err := tg.Send(context.Background(), testMessage)
+	if err != nil || result != Delivered {
+		t.Fatalf("Send = %v, %v", result, err)
+	}
+	req := srv.only(t)
+	if req.Method != http.MethodPost || req.Path != "/bot"+testBotToken+"/sendMessage" {
+		t.Errorf("request = %s %s", req.Method, req.Path)
+	}
+	var got telegramMessage
+	if err := json.Unmarshal(req.Body, &got); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	want := telegramMessage{ChatID: "@media", Text: "/play " + testMessage.StreamURL + "\nBBC One\nSent by alice", DisableWebPagePreview: true}
+	if got != want {
+		t.Errorf("message = %+v, want %+v", got, want)
+	}
+}
+
+func TestTelegramErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string // Empty uses the test server
+		status int
+		body   string
+		want   string // Expected in the error
+	}{
+		{name: "not ok", status: http.StatusOK, body: `{"ok":false,"description":"Bad Request: chat not found"}`,
+			want: "Telegram rejected the message: Bad Request: chat not found"},
+		{name: "unauthorized", status: http.StatusUnauthorized, body: `{"ok":false,"description":"Unauthorized"}`,
+			want: "status code 401"},
+		{name: "unreachable", url: closedURL(), want: "Failed to send request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiURL := tt.url
+			if apiURL == "" {
+				apiURL = newRecorder(t, respond(tt.status, "application/json", tt.body)).URL
+			}
+			tg := NewTelegram(config.SinkConfig{Name: "telegram", URL: apiURL, Token: testBotToken, ChatID: "1"}, testClient())
+
+			result, err := tg.Send(context.Background(), testMessage)
+			if result != Dropped || err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Send = %v, %v; want an error containing %q", result, err, tt.want)
+			}
+			if strings.Contains(err.Error(), "bot-secret") {
+				t.Errorf("error leaks the bot token: %v", err)
+			}
+		})
+	}
+}
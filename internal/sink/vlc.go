@@ -0,0 +1,154 @@
+package sink
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// VLC drives a VLC instance through its Lua HTTP interface, started with
+// --extraintf=http --http-password=...
+type VLC struct {
+	name     string
+	baseURL  string
+	password string
+	client   *http.Client
+	sent     sentTitles
+}
+
+// vlcFullVolume is the VLC volume that plays at 100%
+const vlcFullVolume = 256
+
+var _ Player = (*VLC)(nil)
+
+// NewVLC creates a VLC sink for the HTTP interface at the URL, e.g. http://host:8080
+func NewVLC(cfg config.SinkConfig, client *http.Client) *VLC {
+	return &VLC{name: cfg.Name, baseURL: strings.TrimSuffix(cfg.URL, "/"), password: cfg.Password, client: client}
+}
+
+// Name returns the sink's name
+func (v *VLC) Name() string {
+	return v.name
+}
+
+// Send replaces VLC's playlist item with the stream and plays it
+func (v *VLC) Send(ctx context.Context, msg Message) (Result, error) {
+	if _, err := v.status(ctx, url.Values{"command": {"in_play"}, "input": {msg.StreamURL}}); err != nil {
+		return Dropped, err
+	}
+	v.sent.remember(msg.StreamURL, msg.NowPlaying.Title)
+	return Delivered, nil
+}
+
+// Control stops playback, toggles pause or sets the volume
+func (v *VLC) Control(ctx context.Context, action Action, value int) error {
+	var query url.Values
+	switch action {
+	case Stop:
+		query = url.Values{"command": {"pl_stop"}}
+	case Pause:
+		query = url.Values{"command": {"pl_pause"}}
+	case Volume:
+		query = url.Values{"command": {"volume"}, "val": {fmt.Sprint(value * vlcFullVolume / 100)}}
+	default:
+		return ErrUnsupported
+	}
+	_, err := v.status(ctx, query)
+	return err
+}
+
+// State reads the playback state and volume, and the playing item from the playlist
+func (v *VLC) State(ctx context.Context) (PlayerState, error) {
+	state := PlayerState{Volume: -1}
+	status, err := v.status(ctx, nil)
+	if err != nil {
+		return state, err
+	}
+	state.Volume = (status.Volume*100 + vlcFullVolume/2) / vlcFullVolume
+	if status.State == "stopped" {
+		return state, nil
+	}
+
+	state.Playing = true
+	state.Paused = status.State == "paused"
+	title := status.Information.Category.Meta.Title
+	if title == "" {
+		title = status.Information.Category.Meta.Filename
+	}
+	var playlist vlcNode
+	if err := v.get(ctx, "/requests/playlist.json", nil, &playlist); err == nil {
+		if current, ok := playlist.current(); ok {
+			state.Path = current.URI
+			if title == "" {
+				title = current.Name
+			}
+		}
+	}
+	state.Title = v.sent.titleFor(state.Path, title)
+	return state, nil
+}
+
+// vlcStatus is the part of status.json the sink reads
+type vlcStatus struct {
+	State       string `json:"state"` // "playing", "paused" or "stopped"
+	Volume      int    `json:"volume"`
+	Information struct {
+		Category struct {
+			Meta struct {
+				Title    string `json:"title"`
+				Filename string `json:"filename"`
+			} `json:"meta"`
+		} `json:"category"`
+	} `json:"information"`
+}
+
+// vlcNode is an entry of playlist.json, which nests items under their playlist
+type vlcNode struct {
+	Name     string    `json:"name"`
+	URI      string    `json:"uri"`
+	Current  string    `json:"current"` // "current" on the playing item
+	Children []vlcNode `json:"children"`
+}
+
+func (n vlcNode) current() (vlcNode, bool) {
+	if n.Current == "current" {
+		return n, true
+	}
+	for _, child := range n.Children {
+		if found, ok := child.current(); ok {
+			return found, true
+		}
+	}
+	return vlcNode{}, false
+}
+
+// status runs a command through status.json, which answers with the resulting state
+func (v *VLC) status(ctx context.Context, query url.Values) (vlcStatus, error) {
+	var status vlcStatus
+	err := v.get(ctx, "/requests/status.json", query, &status)
+	return status, err
+}
+
+// get requests a JSON document from the interface. VLC uses basic auth with an
+// empty user name.
+func (v *VLC) get(ctx context.Context, path string, query url.Values, into any) error {
+	endpoint := v.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	header := http.Header{"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(":"+v.password))}}
+	body, err := send(ctx, v.client, http.MethodGet, endpoint, header, "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("VLC request failed: %w", err)
+	}
+	if err := json.Unmarshal(body, into); err != nil {
+		return fmt.Errorf("Failed to decode VLC response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package sink
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// vlcServer serves status.json and playlist.json
+func vlcServer(t *testing.T, status, playlist string) *recorder {
+	return newRecorder(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, pass, _ := r.BasicAuth(); pass != "vlc-pass" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/requests/status.json":
+			w.Write([]byte(status))
+		case "/requests/playlist.json":
+			w.Write([]byte(playlist))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func TestVLCSend(t *testing.T) {
+	srv := vlcServer(t, `{"state":"playing","volume":256}`, `{}`)
+	v := NewVLC(config.SinkConfig{Name: "vlc", URL: srv.URL + "/", Password: "vlc-pass"}, testClient())
+
+	result, err := v.Send(context.Background(), testMessage)
+	if err != nil || result != Delivered {
+		t.Fatalf("Send = %v, %v", result, err)
+	}
+	req := srv.only(t)
+	if req.Method != http.MethodGet || req.Path != "/requests/status.json" {
+		t.Errorf("request = %s %s", req.Method, req.Path)
+	}
+	if req.Query.Get("command") != "in_play" || req.Query.Get("input") != testMessage.StreamURL {
+		t.Errorf("query = %v", req.Query)
+	}
+	if user, pass, ok := (&http.Request{Header: req.Header}).BasicAuth(); !ok || user != "" || pass != "vlc-pass" {
+		t.Errorf("basic auth = %q, %q, %v", user, pass, ok)
+	}
+}
+
+func TestVLCControl(t *testing.T) {
+	tests := []struct {
+		action Action
+		value  int
+		want   map[string]string
+	}{
+		{Stop, 0, map[string]string{"command": "pl_stop"}},
+		{Pause, 0, map[string]string{"command": "pl_pause"}},
+		{Volume, 50, map[string]string{"command": "volume", "val": "128"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.action), func(t *testing.T) {
+			srv := vlcServer(t, `{"state":"playing"}`, `{}`)
+			v := NewVLC(config.SinkConfig{Name: "vlc", URL: srv.URL, Password: "vlc-pass"}, testClient())
+			if err := v.Control(context.Background(), tt.action, tt.value); err != nil {
+				t.Fatal(err)
+			}
+			query := srv.only(t).Query
+			for name, value := range tt.want {
+				if query.Get(name) != value {
+					t.Errorf("%s = %q, want %q", name, query.Get(name), value)
+				}
+			}
+		})
+	}
+}
+
+func TestVLCState(t *testing.T) {
+	playlist := `{"name":"","children":[{"name":"Playlist","children":[
+		{"name":"other.ts","uri":"http://example/other.ts","current":""},
+		{"name":"1.ts","uri":"` + testMessage.StreamURL + `","current":"current"}]}]}`
+	srv := vlcServer(t, `{"state":"paused","volume":128,"information":{"category":{"meta":{"filename":"1.ts"}}}}`, playlist)
+	v := NewVLC(config.SinkConfig{Name: "vlc", URL: srv.URL, Password: "vlc-pass"}, testClient())
+	if _, err := v.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := v.State(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PlayerState{Playing: true, Title: "BBC One", Path: testMessage.StreamURL, Paused: true, Volume: 50}
+	if state != want {
+		t.Errorf("state = %+v, want %+v", state, want)
+	}
+
+	stopped := vlcServer(t, `{"state":"stopped","volume":0}`, `{}`)
+	state, err = NewVLC(config.SinkConfig{Name: "vlc", URL: stopped.URL, Password: "vlc-pass"}, testClient()).State(context.Background())
+	if err != nil || state != (PlayerState{Volume: 0}) {
+		t.Errorf("stopped state = %+v, %v", state, err)
+	}
+}
+
+func TestVLCErrorsHideStreamURL(t *testing.T) {
+	srv := vlcServer(t, `{}`, `{}`)
+	for name, url := range map[string]string{"wrong password": srv.URL, "unreachable": closedURL()} {
+		t.Run(name, func(t *testing.T) {
+			v := NewVLC(config.SinkConfig{Name: "vlc", URL: url, Password: "wrong"}, testClient())
+			result, err := v.Send(context.Background(), testMessage)
+			if result != Dropped || err == nil {
+				t.Fatalf("Send = %v, %v", result, err)
+			}
+			if strings.Contains(err.Error(), "provider.example") || strings.Contains(err.Error(), "pass/1.ts") {
+				t.Errorf("error leaks the stream URL: %v", err)
+			}
+		})
+	}
+}
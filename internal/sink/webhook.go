@@ -0,0 +1,84 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// Webhook posts to a generic HTTP endpoint. The body is rendered from a
+// text/template, or is the JSON encoding of the send when no template is set.
+type Webhook struct {
+	name   string
+	url    string
+	prefix string
+	body   *template.Template // Nil sends the default JSON body
+	client *http.Client
+}
+
+// WebhookData is what a webhook body template is rendered with
+type WebhookData struct {
+	Command    string     `json:"command"` // Prefix and stream URL
+	StreamURL  string     `json:"stream_url"`
+	NowPlaying NowPlaying `json:"now_playing"`
+}
+
+var _ Sink = (*Webhook)(nil)
+
+// NewWebhook creates a generic webhook sink, parsing its body template
+func NewWebhook(cfg config.SinkConfig, client *http.Client) (*Webhook, error) {
+	w := &Webhook{name: cfg.Name, url: cfg.URL, prefix: cfg.Prefix, client: client}
+	if cfg.Template != "" {
+		body, err := ParseTemplate(cfg.Name, cfg.Template)
+		if err != nil {
+			return nil, err
+		}
+		w.body = body
+	}
+	return w, nil
+}
+
+// ParseTemplate parses a webhook body template. Besides the standard functions
+// templates can use json, which encodes a value as JSON, e.g. {{json .Command}}.
+func ParseTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid webhook template: %w", err)
+	}
+	return t, nil
+}
+
+// Name returns the sink's name
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+// Send posts the rendered body to the webhook
+func (w *Webhook) Send(ctx context.Context, msg Message) (Result, error) {
+	data := WebhookData{
+		Command:    Command(w.prefix, msg.StreamURL),
+		StreamURL:  msg.StreamURL,
+		NowPlaying: msg.NowPlaying,
+	}
+	if w.body == nil {
+		_, err := sendJSON(ctx, w.client, http.MethodPost, w.url, nil, data)
+		return result(err)
+	}
+
+	var body bytes.Buffer
+	if err := w.body.Execute(&body, data); err != nil {
+		return Dropped, fmt.Errorf("Failed to render webhook template: %w", err)
+	}
+	_, err := send(ctx, w.client, http.MethodPost, w.url, nil, "application/json", body.Bytes())
+	return result(err)
+}
@@ -0,0 +1,74 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+var testMessage = Message{
+	StreamURL:  "http://provider.example/live/user/pass/1.ts",
+	NowPlaying: NowPlaying{Title: "BBC One", Logo: "http://logo.example/bbc.png", Type: "live", User: "alice"},
+}
+
+func TestWebhookSendsJSON(t *testing.T) {
+	srv := newRecorder(t, respond(http.StatusNoContent, "", ""))
+	w, err := NewWebhook(config.SinkConfig{Name: "hook", URL: srv.URL + "/play", Prefix: "!play"}, testClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := w.Send(context.Background(), testMessage)
+	if err != nil || result != Delivered {
+		t.Fatalf("Send = %v, %v", result, err)
+	}
+	req := srv.only(t)
+	if req.Method != http.MethodPost || req.Path != "/play" {
+		t.Errorf("request = %s %s, want POST /play", req.Method, req.Path)
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var got WebhookData
+	if err := json.Unmarshal(req.Body, &got); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if got.Command != "!play "+testMessage.StreamURL || got.StreamURL != testMessage.StreamURL || got.NowPlaying.Title != "BBC One" || got.NowPlaying.User != "alice" {
+		t.Errorf("body = %+v", got)
+	}
+}
+
+func TestWebhookRendersTemplate(t *testing.T) {
+	srv := newRecorder(t, respond(http.StatusOK, "", ""))
+	w, err := NewWebhook(config.SinkConfig{Name: "hook", URL: srv.URL, Template: `{"url":{{json .StreamURL}},"title":{{json .NowPlaying.Title}}}`}, testClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"url":"http://provider.example/live/user/pass/1.ts","title":"BBC One"}`
+	if body := string(srv.only(t).Body); body != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+}
+
+func TestWebhookErrors(t *testing.T) {
+	if _, err := NewWebhook(config.SinkConfig{Name: "hook", Template: "{{.Missing"}, testClient()); err == nil {
+		t.Error("expected an invalid template to be rejected")
+	}
+
+	srv := newRecorder(t, respond(http.StatusNotFound, "text/plain", "no such hook"))
+	w, err := NewWebhook(config.SinkConfig{Name: "hook", URL: srv.URL}, testClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := w.Send(context.Background(), testMessage)
+	if result != Dropped || err == nil || err.Error() != "Request failed with status code 404: no such hook" {
+		t.Errorf("Send = %v, %v", result, err)
+	}
+}
@@ -0,0 +1,150 @@
+package xtream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// AccountInfo holds the user_info and server_info returned by player_api.php
+type AccountInfo struct {
+	Username             string
+	Status               string
+	ExpiresAt            time.Time // Zero when the subscription never expires
+	CreatedAt            time.Time
+	IsTrial              bool
+	ActiveConnections    int
+	MaxConnections       int
+	AllowedOutputFormats []string
+	ServerURL            string
+	ServerTimezone       string
+	ServerTimeNow        string
+}
+
+// Warnings returns human-readable problems with the subscription, such as an
+// upcoming expiry or all connections being in use
+func (a *AccountInfo) Warnings(expiryWarningDays int, now time.Time) []string {
+	var warnings []string
+	if a.Status != "" && a.Status != "Active" {
+		warnings = append(warnings, fmt.Sprintf("Subscription status is %q", a.Status))
+	}
+	if !a.ExpiresAt.IsZero() {
+		remaining := a.ExpiresAt.Sub(now)
+		switch {
+		case remaining <= 0:
+			warnings = append(warnings, fmt.Sprintf("Subscription expired on %s", a.ExpiresAt.Format("2006-01-02")))
+		case remaining < time.Duration(expiryWarningDays)*24*time.Hour:
+			warnings = append(warnings, fmt.Sprintf("Subscription expires in %d days (%s)",
+				int(remaining.Hours()/24), a.ExpiresAt.Format("2006-01-02")))
+		}
+	}
+	if a.MaxConnections > 0 && a.ActiveConnections >= a.MaxConnections {
+		warnings = append(warnings, fmt.Sprintf("All %d connections are in use", a.MaxConnections))
+	}
+	return warnings
+}
+
+// FetchAccountInfo fetches the account and server details from the Xtream Code API
+func (c *Client) FetchAccountInfo(ctx context.Context) (*AccountInfo, error) {
+	url := fmt.Sprintf("%s/player_api.php?username=%s&password=%s",
+		c.BaseURL, c.Username, c.Password)
+
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch account info: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code for account info: %d", resp.StatusCode)
+	}
+
+	var raw struct {
+		UserInfo struct {
+			Username             string   `json:"username"`
+			Status               string   `json:"status"`
+			ExpDate              any      `json:"exp_date"`
+			CreatedAt            any      `json:"created_at"`
+			IsTrial              any      `json:"is_trial"`
+			ActiveCons           any      `json:"active_cons"`
+			MaxConnections       any      `json:"max_connections"`
+			AllowedOutputFormats []string `json:"allowed_output_formats"`
+		} `json:"user_info"`
+		ServerInfo struct {
+			URL      string `json:"url"`
+			Timezone string `json:"timezone"`
+			TimeNow  string `json:"time_now"`
+		} `json:"server_info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode account info: %w", err)
+	}
+
+	info := &AccountInfo{
+		Username:             raw.UserInfo.Username,
+		Status:               raw.UserInfo.Status,
+		IsTrial:              intValue(raw.UserInfo.IsTrial) == 1,
+		ActiveConnections:    intValue(raw.UserInfo.ActiveCons),
+		MaxConnections:       intValue(raw.UserInfo.MaxConnections),
+		AllowedOutputFormats: raw.UserInfo.AllowedOutputFormats,
+		ServerURL:            raw.ServerInfo.URL,
+		ServerTimezone:       raw.ServerInfo.Timezone,
+		ServerTimeNow:        raw.ServerInfo.TimeNow,
+	}
+	if exp := intValue(raw.UserInfo.ExpDate); exp > 0 {
+		info.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	if created := intValue(raw.UserInfo.CreatedAt); created > 0 {
+		info.CreatedAt = time.Unix(int64(created), 0)
+	}
+
+	return info, nil
+}
+
+// GetAccountInfo fetches the account details, using the cache if available,
+// and logs any subscription warnings on a fresh fetch
+func (c *Client) GetAccountInfo(ctx context.Context) (*AccountInfo, error) {
+	if cached, ok := c.AccountCache.Get(); ok {
+		return &cached, nil
+	}
+
+	info, err := c.FetchAccountInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.AccountCache.Set(*info, time.Minute*5)
+
+	// Fall back to the provider's own timezone for timeshift URLs when none is configured
+	if !c.timezoneConfigured && info.ServerTimezone != "" {
+		if loc, err := time.LoadLocation(info.ServerTimezone); err == nil {
+			c.mu.Lock()
+			c.timeshiftLocation = loc
+			c.mu.Unlock()
+		}
+	}
+
+	for _, warning := range info.Warnings(c.AccountWarningDays, time.Now()) {
+		slog.Warn("Xtream account warning", "provider", c.name, "warning", warning, "username", info.Username)
+	}
+
+	return info, nil
+}
+
+// monitorAccount checks the subscription hourly so problems show up in the logs
+// before sends start failing
+func (c *Client) monitorAccount() {
+	ctx := context.Background()
+	if _, err := c.GetAccountInfo(ctx); err != nil {
+		slog.Warn("Failed to fetch Xtream account info", "provider", c.name, "error", err)
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := c.GetAccountInfo(ctx); err != nil {
+			slog.Warn("Failed to fetch Xtream account info", "provider", c.name, "error", err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package xtream
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -9,39 +10,73 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/git-saj/go-media-control/internal/cache"
 	"github.com/git-saj/go-media-control/internal/config"
 )
 
+// healthCheckInterval is how long a provider's last response is trusted before
+// a send probes it again
+const healthCheckInterval = 2 * time.Minute
+
 // Client represents an Xtream Code API client
 type Client struct {
-	BaseURL            string
-	Username           string
-	Password           string
-	Cache              *cache.Cache[[]MediaItem]
-	CategoryCache      *cache.Cache[[]Category]
-	EpgCache           *cache.Cache[map[int]EpgData]
-	httpClient         *http.Client
-	mu                 sync.RWMutex
-	streamURLs         map[int]string
-	EpgFetchTime       time.Time
-	streamIDs          []int
-	disableEpgPrefetch bool
+	name                string // Provider name, used to namespace its catalogue
+	BaseURL             string
+	Username            string
+	Password            string
+	Cache               *cache.Cache[[]MediaItem]
+	CategoryCache       *cache.Cache[[]Category]
+	EpgCache            *cache.Keyed[int, EpgData]
+	XmltvCache          *cache.Cache[map[string][]EpgListing]
+	VodCache            *cache.Cache[[]MediaItem]
+	VodCategoryCache    *cache.Cache[[]Category]
+	SeriesCache         *cache.Cache[[]MediaItem]
+	SeriesCategoryCache *cache.Cache[[]Category]
+	AccountCache        *cache.Cache[AccountInfo]
+	AccountWarningDays  int
+	timeout             time.Duration // Per-attempt limit for player_api calls
+	xmltvTimeout        time.Duration // Per-attempt limit for the XMLTV download
+	retries             int
+	retryBackoff        time.Duration
+	httpClient          *http.Client
+	mu                  sync.RWMutex
+	streamURLs          map[int]string
+	vodURLs             map[int]string
+	episodes            *cache.Keyed[int, episodeEntry] // Episodes of the series opened recently, for sending
+	archiveDays         map[int]int
+	epgChannelIDs       map[int]string
+	channelNames        map[int]string
+	lastFailure         time.Time // When the most recent player_api request failed
+	lastSuccess         time.Time
+	timeshiftLocation   *time.Location
+	timezoneConfigured  bool
+	outputFormat        string
+	EpgFetchTime        time.Time
+	streamIDs           []int
+	disableEpgPrefetch  bool
+	prefetchPolicy      *PrefetchPolicy
 }
 
 // MediaItem represents a single media item from the Xtream Code API
 type MediaItem struct {
-	Name           string `json:"name"`
-	StreamID       int    `json:"stream_id"` // From API response
-	Logo           string `json:"stream_icon"`
-	StreamURL      string `json:"stream_url"`
-	CategoryID     string `json:"category_id"`
-	CurrentProgram *EpgListing
-	NextProgram    *EpgListing
+	Provider           string `json:"provider"` // Name of the provider serving the item
+	Name               string `json:"name"`
+	StreamID           int    `json:"stream_id"` // From API response
+	Logo               string `json:"stream_icon"`
+	StreamURL          string `json:"stream_url"`
+	CategoryID         string `json:"category_id"`
+	EpgChannelID       string `json:"epg_channel_id,omitempty"`
+	StreamType         string `json:"stream_type"`
+	ContainerExtension string `json:"container_extension,omitempty"`
+	Rating             string `json:"rating,omitempty"`
+	TVArchive          bool   `json:"tv_archive"`
+	TVArchiveDuration  int    `json:"tv_archive_duration"` // Catch-up window in days
+	CurrentProgram     *EpgListing
+	NextProgram        *EpgListing
 }
 
 // EpgListing represents a single EPG entry for a media item
@@ -68,21 +103,52 @@ type Category struct {
 	CategoryName string `json:"category_name"`
 }
 
-// NewClient creates a new Xtream Code API client from the configuration
-func NewClient(cfg *config.Config) *Client {
+// NewClient creates a new Xtream Code API client for one configured provider
+func NewClient(cfg *config.Config, provider config.XtreamProvider) *Client {
+	// Timeshift start times are interpreted in the provider's timezone
+	location := time.Local
+	timezoneConfigured := false
+	if provider.Timezone != "" {
+		if loc, err := time.LoadLocation(provider.Timezone); err == nil {
+			location = loc
+			timezoneConfigured = true
+		}
+	}
+
 	client := &Client{
-		BaseURL:            cfg.XtreamBaseURL,
-		Username:           cfg.XtreamUsername,
-		Password:           cfg.XtreamPassword,
-		Cache:              cache.New[[]MediaItem](),
-		CategoryCache:      cache.New[[]Category](),
-		EpgCache:           cache.New[map[int]EpgData](),
-		httpClient:         &http.Client{},
-		EpgFetchTime:       time.Time{},
-		streamIDs:          []int{},
-		streamURLs:         make(map[int]string),
-		mu:                 sync.RWMutex{},
-		disableEpgPrefetch: cfg.DisableEpgPrefetch,
+		name:                provider.Name,
+		BaseURL:             provider.BaseURL,
+		Username:            provider.Username,
+		Password:            provider.Password,
+		Cache:               cache.New[[]MediaItem](),
+		CategoryCache:       cache.New[[]Category](),
+		EpgCache:            cache.NewKeyed[int, EpgData](cfg.EpgCacheSize),
+		XmltvCache:          cache.New[map[string][]EpgListing](),
+		VodCache:            cache.New[[]MediaItem](),
+		VodCategoryCache:    cache.New[[]Category](),
+		SeriesCache:         cache.New[[]MediaItem](),
+		SeriesCategoryCache: cache.New[[]Category](),
+		AccountCache:        cache.New[AccountInfo](),
+		AccountWarningDays:  cfg.AccountExpiryWarningDays,
+		timeout:             cfg.UpstreamTimeout,
+		xmltvTimeout:        cfg.XmltvTimeout,
+		retries:             cfg.UpstreamRetries,
+		retryBackoff:        cfg.RetryBackoff,
+		httpClient:          &http.Client{},
+		EpgFetchTime:        time.Time{},
+		streamIDs:           []int{},
+		streamURLs:          make(map[int]string),
+		vodURLs:             make(map[int]string),
+		episodes:            cache.NewKeyed[int, episodeEntry](episodeCacheSize),
+		archiveDays:         make(map[int]int),
+		epgChannelIDs:       make(map[int]string),
+		channelNames:        make(map[int]string),
+		timeshiftLocation:   location,
+		timezoneConfigured:  timezoneConfigured,
+		outputFormat:        cfg.OutputFormat,
+		mu:                  sync.RWMutex{},
+		disableEpgPrefetch:  cfg.DisableEpgPrefetch,
+		prefetchPolicy:      NewPrefetchPolicy(cfg),
 	}
 
 	// Start background EPG prefetching only if not disabled
@@ -90,15 +156,54 @@ func NewClient(cfg *config.Config) *Client {
 		go client.prefetchEPGs()
 	}
 
+	// Watch the subscription for upcoming expiry and exhausted connections
+	go client.monitorAccount()
+
 	return client
 }
 
+// Name returns the provider name, which namespaces its catalogue
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Healthy reports whether the provider answered its most recent request
+func (c *Client) Healthy() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !c.lastFailure.After(c.lastSuccess)
+}
+
+// checkHealth is Healthy, but probes player_api first when the provider has not
+// been contacted recently
+func (c *Client) checkHealth(ctx context.Context) bool {
+	c.mu.RLock()
+	stale := time.Since(c.lastSuccess) > healthCheckInterval && time.Since(c.lastFailure) > healthCheckInterval
+	c.mu.RUnlock()
+
+	if stale {
+		if _, err := c.FetchAccountInfo(ctx); err != nil {
+			slog.Warn("Provider health check failed", "provider", c.name, "error", err)
+		}
+	}
+	return c.Healthy()
+}
+
+// ChannelIdentity returns the name and EPG channel ID of a live stream, used to
+// find the same channel on another provider
+func (c *Client) ChannelIdentity(streamID int) (string, string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	name, ok := c.channelNames[streamID]
+	return name, c.epgChannelIDs[streamID], ok
+}
+
 // FetchLiveStreams fetches live streams from the Xtream Code API and constructs StreamURL
-func (c *Client) fetchLiveStreams() ([]MediaItem, error) {
+func (c *Client) fetchLiveStreams(ctx context.Context) ([]MediaItem, error) {
 	url := fmt.Sprintf("%s/player_api.php?username=%s&password=%s&action=get_live_streams",
 		c.BaseURL, c.Username, c.Password)
 
-	resp, err := c.httpClient.Get(url)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch live streams: %w", err)
 	}
@@ -109,10 +214,13 @@ func (c *Client) fetchLiveStreams() ([]MediaItem, error) {
 	}
 
 	var rawMedia []struct {
-		Name       string      `json:"name"`
-		StreamID   json.Number `json:"stream_id"` // From API response, as json.Number for flexibility
-		Logo       string      `json:"stream_icon"`
-		CategoryID json.Number `json:"category_id"`
+		Name              string      `json:"name"`
+		StreamID          json.Number `json:"stream_id"` // From API response, as json.Number for flexibility
+		Logo              string      `json:"stream_icon"`
+		CategoryID        json.Number `json:"category_id"`
+		TVArchive         any         `json:"tv_archive"`
+		TVArchiveDuration any         `json:"tv_archive_duration"`
+		EpgChannelID      string      `json:"epg_channel_id"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&rawMedia); err != nil {
 		return nil, fmt.Errorf("failed to decode live streams: %w", err)
@@ -123,72 +231,104 @@ func (c *Client) fetchLiveStreams() ([]MediaItem, error) {
 	for i, item := range rawMedia {
 		streamIDInt, _ := strconv.Atoi(string(item.StreamID))
 		media[i] = MediaItem{
-			Name:       item.Name,
-			StreamID:   streamIDInt,
-			Logo:       item.Logo,
-			CategoryID: string(item.CategoryID),
-			StreamURL: fmt.Sprintf("%s/%s/%s/%d.ts",
-				c.BaseURL, c.Username, c.Password, streamIDInt),
+			Provider:          c.name,
+			Name:              item.Name,
+			StreamID:          streamIDInt,
+			Logo:              item.Logo,
+			CategoryID:        string(item.CategoryID),
+			StreamType:        StreamTypeLive,
+			EpgChannelID:      item.EpgChannelID,
+			TVArchive:         intValue(item.TVArchive) == 1,
+			TVArchiveDuration: intValue(item.TVArchiveDuration),
+			StreamURL:         c.liveStreamURL(streamIDInt, c.outputFormat),
 		}
 	}
 
-	c.streamIDs = make([]int, 0, len(media))
-	for _, m := range media {
-		c.streamIDs = append(c.streamIDs, m.StreamID)
-	}
-
 	return media, nil
 }
 
-// GetLiveStreams retrieves live streams with caching and EPG prefetching
-func (c *Client) GetLiveStreams() ([]MediaItem, error) {
-	c.mu.RLock()
-	if items, ok := c.Cache.Get(); ok {
-		slog.Info("LiveStreams cache hit")
-		// Always populate streamURLs to keep it in sync
-		c.mu.RUnlock()
-		c.mu.Lock()
-		c.streamURLs = make(map[int]string)
-		for _, m := range items {
-			c.streamURLs[m.StreamID] = m.StreamURL
-		}
-		c.mu.Unlock()
-		return items, nil
-	}
-	c.mu.RUnlock()
+// GetLiveStreams retrieves live streams with caching and EPG prefetching. Expired
+// streams keep being served while they are refreshed in the background.
+func (c *Client) GetLiveStreams(ctx context.Context) ([]MediaItem, error) {
+	return c.Cache.Load(ctx, time.Minute*10, c.loadLiveStreams)
+}
 
-	items, err := c.fetchLiveStreams()
+// loadLiveStreams fetches the live streams, indexes them and starts the EPG
+// prefetch when it is due
+func (c *Client) loadLiveStreams(ctx context.Context) ([]MediaItem, error) {
+	items, err := c.fetchLiveStreams(ctx)
 	if err != nil {
 		return nil, err
 	}
+	c.indexLiveStreams(items)
 
+	// Prefetch EPG asynchronously if needed and not disabled
 	c.mu.Lock()
-	c.Cache.Set(items, time.Minute*10)
+	if !c.disableEpgPrefetch && (c.EpgFetchTime.IsZero() || time.Since(c.EpgFetchTime) > 24*time.Hour) {
+		c.EpgFetchTime = time.Now()
+		// Detached from ctx so the prefetch outlives the request that triggered it
+		go c.doPrefetchEPGs(context.Background())
+	}
+	c.mu.Unlock()
+
+	return items, nil
+}
+
+// indexLiveStreams records the live streams' URLs and identities for sending,
+// EPG and failover lookups
+func (c *Client) indexLiveStreams(items []MediaItem) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.streamIDs = make([]int, 0, len(items))
 	for _, m := range items {
 		c.streamIDs = append(c.streamIDs, m.StreamID)
 	}
 	c.streamURLs = make(map[int]string)
+	c.archiveDays = make(map[int]int)
+	c.epgChannelIDs = make(map[int]string)
+	c.channelNames = make(map[int]string)
 	for _, m := range items {
 		c.streamURLs[m.StreamID] = m.StreamURL
+		c.channelNames[m.StreamID] = m.Name
+		if m.EpgChannelID != "" {
+			c.epgChannelIDs[m.StreamID] = m.EpgChannelID
+		}
+		if m.TVArchive {
+			c.archiveDays[m.StreamID] = m.TVArchiveDuration
+		}
 	}
+}
 
-	// Prefetch EPG asynchronously if needed and not disabled
-	if !c.disableEpgPrefetch && (c.EpgFetchTime.IsZero() || time.Since(c.EpgFetchTime) > 24*time.Hour) {
-		c.EpgFetchTime = time.Now()
-		go c.doPrefetchEPGs()
+// Persist restores the catalogue and EPG caches from their snapshots in store
+// and saves them there from now on, so a restart serves the last catalogue
+// while it is refreshed. A nil store does nothing.
+func (c *Client) Persist(store *cache.Store) {
+	prefix := "xtream-" + c.name + "-"
+	c.Cache.Persist(store, prefix+"live")
+	c.CategoryCache.Persist(store, prefix+"live-categories")
+	c.VodCache.Persist(store, prefix+"vod")
+	c.VodCategoryCache.Persist(store, prefix+"vod-categories")
+	c.SeriesCache.Persist(store, prefix+"series")
+	c.SeriesCategoryCache.Persist(store, prefix+"series-categories")
+	c.EpgCache.Persist(store, prefix+"epg")
+	c.XmltvCache.Persist(store, prefix+"xmltv")
+
+	// Restored catalogues are not loaded through the API, so index them here
+	if items, ok := c.Cache.Peek(); ok {
+		c.indexLiveStreams(items)
+	}
+	if items, ok := c.VodCache.Peek(); ok {
+		c.indexVodStreams(items)
 	}
-	c.mu.Unlock()
-
-	return items, nil
 }
 
 // FetchCategories fetches live categories from the Xtream Code API
-func (c *Client) FetchCategories() ([]Category, error) {
+func (c *Client) FetchCategories(ctx context.Context) ([]Category, error) {
 	url := fmt.Sprintf("%s/player_api.php?username=%s&password=%s&action=get_live_categories",
 		c.BaseURL, c.Username, c.Password)
 
-	resp, err := c.httpClient.Get(url)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch categories: %w", err)
 	}
@@ -207,30 +347,16 @@ func (c *Client) FetchCategories() ([]Category, error) {
 }
 
 // GetCategories fetches categories, using the cache if available
-func (c *Client) GetCategories() ([]Category, error) {
-	// Check cache first
-	if cached, ok := c.CategoryCache.Get(); ok {
-		return cached, nil
-	}
-
-	// Fetch from API if cache is empty or expired
-	categories, err := c.FetchCategories()
-	if err != nil {
-		return nil, err
-	}
-
-	// Store in cache with a 24-hour TTL
-	c.CategoryCache.Set(categories, time.Hour*24)
-
-	return categories, nil
+func (c *Client) GetCategories(ctx context.Context) ([]Category, error) {
+	return c.CategoryCache.Load(ctx, time.Hour*24, c.FetchCategories)
 }
 
 // FetchEpgForStream fetches EPG data for a specific stream
-func (c *Client) FetchEpgForStream(streamID int) ([]EpgListing, string, error) {
+func (c *Client) FetchEpgForStream(ctx context.Context, streamID int) ([]EpgListing, string, error) {
 	url := fmt.Sprintf("%s/player_api.php?username=%s&password=%s&action=get_epg&stream_id=%d",
 		c.BaseURL, c.Username, c.Password, streamID)
 
-	resp, err := c.httpClient.Get(url)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to fetch EPG for stream %d: %w", streamID, err)
 	}
@@ -273,27 +399,39 @@ func (c *Client) prefetchEPGs() {
 	for {
 		select {
 		case <-ticker.C:
-			c.doPrefetchEPGs()
+			// Mark the run so the live stream refresh it causes does not start another
+			c.mu.Lock()
+			c.EpgFetchTime = time.Now()
+			c.mu.Unlock()
+			c.doPrefetchEPGs(context.Background())
 		}
 	}
 }
 
-func (c *Client) doPrefetchEPGs() {
+func (c *Client) doPrefetchEPGs(ctx context.Context) {
 	if c.disableEpgPrefetch {
 		return
 	}
-	// Get current media items from cache
-	c.mu.RLock()
-	items, ok := c.Cache.Get()
-	c.mu.RUnlock()
-	if !ok {
-		return // No cached items
+	start := time.Now()
+
+	// A single XMLTV download covers most channels; per-stream get_epg only fills the gaps
+	xmltvLoaded := true
+	if err := c.LoadXMLTV(ctx); err != nil {
+		xmltvLoaded = false
+		slog.Warn("Failed to load XMLTV, falling back to per-stream EPG prefetch", "provider", c.name, "error", err)
 	}
 
-	// Fetch categories if not cached, to filter UK ones
-	categories, err := c.GetCategories()
+	// Get current media items, waiting for the load that triggered the prefetch
+	items, err := c.GetLiveStreams(ctx)
 	if err != nil {
-		return // Can't filter without categories
+		return
+	}
+
+	// Fetch categories if not cached, so the policy can match on names
+	categories, err := c.GetCategories(ctx)
+	if err != nil {
+		slog.Warn("Failed to fetch categories for EPG prefetch", "provider", c.name, "error", err)
+		return
 	}
 
 	// Create category ID to name map
@@ -302,73 +440,71 @@ func (c *Client) doPrefetchEPGs() {
 		catMap[cat.CategoryID] = cat.CategoryName
 	}
 
+	var fetched, failed atomic.Int64
+	var skipped, fromXmltv int
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, 10) // Limit concurrent requests
 	for _, item := range items {
-		// Only prefetch if category contains "UK"
-		if catName, exists := catMap[item.CategoryID]; exists && strings.Contains(strings.ToLower(catName), "uk") {
-			wg.Add(1)
-			go func(streamID int) {
-				defer wg.Done()
-				sem <- struct{}{}
-				defer func() { <-sem }()
-				// Fetch and cache EPG
-				_, _, err := c.GetEpgForStream(streamID)
-				if err != nil {
-					// Could log error, but for now ignore to avoid spam
-				}
-			}(item.StreamID)
+		if !c.prefetchPolicy.Selects(item, catMap[item.CategoryID]) {
+			skipped++
+			continue
+		}
+		if xmltvLoaded {
+			if _, ok := c.xmltvListings(item.StreamID); ok {
+				fromXmltv++
+				continue
+			}
 		}
+		wg.Add(1)
+		go func(streamID int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// Fetch and cache EPG
+			if _, _, err := c.GetEpgForStream(ctx, streamID); err != nil {
+				failed.Add(1)
+				return
+			}
+			fetched.Add(1)
+		}(item.StreamID)
 	}
 	wg.Wait()
+
+	slog.Info("EPG prefetch completed",
+		"provider", c.name,
+		"fetched", fetched.Load(),
+		"skipped", skipped,
+		"failed", failed.Load(),
+		"from_xmltv", fromXmltv,
+		"duration", time.Since(start))
 }
 
-func (c *Client) GetEpgForStream(streamID int) ([]EpgListing, string, error) {
+func (c *Client) GetEpgForStream(ctx context.Context, streamID int) ([]EpgListing, string, error) {
 	// Check cache first
-	c.mu.RLock()
-	if cachedMap, ok := c.EpgCache.Get(); ok {
-		if epgData, exists := cachedMap[streamID]; exists {
-			// Return a copy to avoid modifying cache
-			epgCopy := make([]EpgListing, len(epgData.Epg))
-			copy(epgCopy, epgData.Epg)
-			c.mu.RUnlock()
-			slog.Info("EPG cache hit", "stream_id", streamID)
-			return epgCopy, epgData.Raw, nil
-		}
+	if epgData, ok := c.EpgCache.Get(streamID); ok {
+		slog.Info("EPG cache hit", "stream_id", streamID)
+		// Return a copy to avoid modifying cache
+		return append([]EpgListing(nil), epgData.Epg...), epgData.Raw, nil
+	}
+
+	// Use the bulk XMLTV index when it has the channel
+	if epg, ok := c.xmltvListings(streamID); ok {
+		slog.Info("EPG XMLTV hit", "stream_id", streamID)
+		return epg, "", nil
 	}
-	c.mu.RUnlock()
 
-	// Fetch from API if cache is empty or expired
-	epg, rawBody, err := c.FetchEpgForStream(streamID)
+	// Fetch from API if not cached or expired
+	epg, rawBody, err := c.FetchEpgForStream(ctx, streamID)
 	if err != nil {
 		return nil, "", err
 	}
 	slog.Info("EPG fetched from API", "stream_id", streamID, "program_count", len(epg))
 
 	// Store parsed epg and raw in cache with a 24-hour TTL
-	c.mu.Lock()
-	if cachedMap, ok := c.EpgCache.Get(); ok {
-		// Copy the existing map to avoid concurrent modification
-		newMap := make(map[int]EpgData, len(cachedMap)+1)
-		for k, v := range cachedMap {
-			newMap[k] = EpgData{
-				Epg: append([]EpgListing(nil), v.Epg...),
-				Raw: v.Raw,
-			}
-		}
-		newMap[streamID] = EpgData{
-			Epg: append([]EpgListing(nil), epg...),
-			Raw: rawBody,
-		}
-		c.EpgCache.Set(newMap, time.Hour*24)
-	} else {
-		newMap := map[int]EpgData{streamID: EpgData{
-			Epg: append([]EpgListing(nil), epg...),
-			Raw: rawBody,
-		}}
-		c.EpgCache.Set(newMap, time.Hour*24)
-	}
-	c.mu.Unlock()
+	c.EpgCache.Set(streamID, EpgData{
+		Epg: append([]EpgListing(nil), epg...),
+		Raw: rawBody,
+	}, time.Hour*24)
 
 	return epg, rawBody, nil
 }
@@ -381,11 +517,16 @@ func (c *Client) GetStreamURL(streamID int) (string, bool) {
 	return url, ok
 }
 
-// ClearCache clears both the data cache, EPG cache, and the URL map
+// ClearCache clears the live, VOD and series caches and the EPG caches so they are
+// refetched. The URL maps are kept so items already on screen can still be sent.
 func (c *Client) ClearCache() {
 	c.Cache.Clear()
 	c.EpgCache.Clear()
+	c.VodCache.Clear()
+	c.SeriesCache.Clear()
+	c.XmltvCache.Clear()
+	// Reset EPG fetch time to force refetch
 	c.mu.Lock()
-	c.streamURLs = make(map[int]string)
+	c.EpgFetchTime = time.Time{}
 	c.mu.Unlock()
 }
@@ -0,0 +1,74 @@
+package xtream
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// Live stream output formats
+const (
+	FormatTS  = "ts"
+	FormatHLS = "m3u8"
+)
+
+// SupportedFormats lists the live output formats this client can build URLs for
+var SupportedFormats = []string{FormatTS, FormatHLS}
+
+// liveStreamURL builds the live stream URL for a stream in the given output format
+func (c *Client) liveStreamURL(streamID int, format string) string {
+	if format == FormatHLS {
+		return fmt.Sprintf("%s/live/%s/%s/%d.%s", c.BaseURL, c.Username, c.Password, streamID, format)
+	}
+	return fmt.Sprintf("%s/%s/%s/%d.%s", c.BaseURL, c.Username, c.Password, streamID, format)
+}
+
+// DefaultFormat returns the configured live output format
+func (c *Client) DefaultFormat() string {
+	return c.outputFormat
+}
+
+// AllowedFormats returns the supported live output formats that the provider's
+// allowed_output_formats permits. If the account can't be checked, or the provider
+// doesn't restrict formats, every supported format is returned.
+func (c *Client) AllowedFormats(ctx context.Context) []string {
+	info, err := c.GetAccountInfo(ctx)
+	if err != nil {
+		slog.Warn("Failed to fetch account info for output formats", "provider", c.name, "error", err)
+		return SupportedFormats
+	}
+	if len(info.AllowedOutputFormats) == 0 {
+		return SupportedFormats
+	}
+
+	var allowed []string
+	for _, format := range SupportedFormats {
+		if slices.Contains(info.AllowedOutputFormats, format) {
+			allowed = append(allowed, format)
+		}
+	}
+	return allowed
+}
+
+// CheckFormat returns an error if a live output format is unsupported or not
+// allowed by the provider
+func (c *Client) CheckFormat(ctx context.Context, format string) error {
+	if !slices.Contains(SupportedFormats, format) {
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+	if format != c.outputFormat && !slices.Contains(c.AllowedFormats(ctx), format) {
+		return fmt.Errorf("output format %q is not allowed by the provider", format)
+	}
+	return nil
+}
+
+// GetStreamURLWithFormat retrieves the live stream URL for a stream ID in a
+// specific output format; an empty format means the configured default
+func (c *Client) GetStreamURLWithFormat(streamID int, format string) (string, bool) {
+	url, ok := c.GetStreamURL(streamID)
+	if !ok || format == "" || format == c.outputFormat {
+		return url, ok
+	}
+	return c.liveStreamURL(streamID, format), true
+}
@@ -0,0 +1,286 @@
+package xtream
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// MultiClient merges the catalogues of several channel sources and falls over
+// between them when a source stops answering. Xtream providers come first, in
+// priority order, followed by any additional sources such as M3U playlists.
+type MultiClient struct {
+	clients []*Client       // Xtream providers, sorted by priority
+	sources []ChannelSource // Every live channel source, clients included
+	byName  map[string]ChannelSource
+}
+
+// ProviderAccount pairs a provider with its subscription details or the error fetching them
+type ProviderAccount struct {
+	Provider string
+	Info     *AccountInfo
+	Err      error
+}
+
+// counterpart is the same channel carried by another source
+type counterpart struct {
+	source   ChannelSource
+	streamID int
+}
+
+// NewMultiClient creates a client for every configured Xtream provider and adds
+// the given extra sources after them
+func NewMultiClient(cfg *config.Config, extra ...ChannelSource) *MultiClient {
+	m := &MultiClient{byName: make(map[string]ChannelSource)}
+	for _, provider := range cfg.XtreamProviders {
+		c := NewClient(cfg, provider)
+		m.clients = append(m.clients, c)
+		m.sources = append(m.sources, c)
+		m.byName[c.Name()] = c
+	}
+	for _, src := range extra {
+		m.sources = append(m.sources, src)
+		m.byName[src.Name()] = src
+	}
+	return m
+}
+
+// Clients returns the Xtream provider clients in priority order
+func (m *MultiClient) Clients() []*Client {
+	return m.clients
+}
+
+// Sources returns every live channel source in priority order
+func (m *MultiClient) Sources() []ChannelSource {
+	return m.sources
+}
+
+// Primary returns the highest-priority Xtream provider, or nil when only other
+// sources are configured
+func (m *MultiClient) Primary() *Client {
+	if len(m.clients) == 0 {
+		return nil
+	}
+	return m.clients[0]
+}
+
+// Source returns the named channel source; an empty name selects the first one
+func (m *MultiClient) Source(name string) (ChannelSource, bool) {
+	if name == "" {
+		if len(m.sources) == 0 {
+			return nil, false
+		}
+		return m.sources[0], true
+	}
+	src, ok := m.byName[name]
+	return src, ok
+}
+
+// Client returns the named Xtream provider; an empty name selects the primary.
+// It reports false for unknown names and for sources that are not Xtream providers.
+func (m *MultiClient) Client(name string) (*Client, bool) {
+	if name == "" {
+		c := m.Primary()
+		return c, c != nil
+	}
+	c, ok := m.byName[name].(*Client)
+	return c, ok
+}
+
+// GetLiveStreams returns the live streams of every reachable source
+func (m *MultiClient) GetLiveStreams(ctx context.Context) ([]MediaItem, error) {
+	return merge(ctx, m.sources, "live streams", ChannelSource.GetLiveStreams, namespaceItem)
+}
+
+// GetVodStreams returns the movies of every reachable provider
+func (m *MultiClient) GetVodStreams(ctx context.Context) ([]MediaItem, error) {
+	return merge(ctx, m.clients, "movies", (*Client).GetVodStreams, namespaceItem)
+}
+
+// GetSeries returns the series of every reachable provider
+func (m *MultiClient) GetSeries(ctx context.Context) ([]MediaItem, error) {
+	return merge(ctx, m.clients, "series", (*Client).GetSeries, namespaceItem)
+}
+
+// GetCategories returns the live categories of every reachable source
+func (m *MultiClient) GetCategories(ctx context.Context) ([]Category, error) {
+	return merge(ctx, m.sources, "live categories", ChannelSource.GetCategories, namespaceCategory)
+}
+
+// GetVodCategories returns the movie categories of every reachable provider
+func (m *MultiClient) GetVodCategories(ctx context.Context) ([]Category, error) {
+	return merge(ctx, m.clients, "movie categories", (*Client).GetVodCategories, namespaceCategory)
+}
+
+// GetSeriesCategories returns the series categories of every reachable provider
+func (m *MultiClient) GetSeriesCategories(ctx context.Context) ([]Category, error) {
+	return merge(ctx, m.clients, "series categories", (*Client).GetSeriesCategories, namespaceCategory)
+}
+
+// GetEpgForStream returns the EPG for a source's channel, reading it from
+// another source carrying the same channel when that one fails
+func (m *MultiClient) GetEpgForStream(ctx context.Context, source string, streamID int) ([]EpgListing, string, error) {
+	src, ok := m.Source(source)
+	if !ok {
+		return nil, "", fmt.Errorf("unknown source %q", source)
+	}
+
+	epg, raw, err := src.GetEpgForStream(ctx, streamID)
+	if err == nil {
+		return epg, raw, nil
+	}
+	for _, alt := range m.counterparts(ctx, src, streamID) {
+		if epg, raw, altErr := alt.source.GetEpgForStream(ctx, alt.streamID); altErr == nil {
+			slog.Info("EPG served by failover source", "source", src.Name(), "failover", alt.source.Name(), "stream_id", streamID)
+			return epg, raw, nil
+		}
+	}
+	return nil, raw, err
+}
+
+// LiveStreamURL returns the URL to send for a source's channel and the source
+// serving it. When the source is failing, the same channel on the next healthy
+// source is used instead.
+func (m *MultiClient) LiveStreamURL(ctx context.Context, source string, streamID int, format string) (string, string, bool) {
+	src, ok := m.Source(source)
+	if !ok {
+		return "", "", false
+	}
+
+	// Xtream providers are probed when they have not been contacted recently
+	healthy := src.Healthy()
+	if c, isClient := src.(*Client); isClient {
+		healthy = c.checkHealth(ctx)
+	}
+
+	url, ok := src.GetStreamURLWithFormat(streamID, format)
+	if ok && healthy {
+		return url, src.Name(), true
+	}
+	for _, alt := range m.counterparts(ctx, src, streamID) {
+		if format != "" && alt.source.CheckFormat(ctx, format) != nil {
+			continue
+		}
+		if altURL, altOK := alt.source.GetStreamURLWithFormat(alt.streamID, format); altOK {
+			slog.Warn("Source failing, sending from failover source", "source", src.Name(), "failover", alt.source.Name(), "stream_id", streamID)
+			return altURL, alt.source.Name(), true
+		}
+	}
+
+	// No other source carries the channel, so try the original anyway
+	return url, src.Name(), ok
+}
+
+// Accounts returns the subscription details of every Xtream provider in priority order
+func (m *MultiClient) Accounts(ctx context.Context) []ProviderAccount {
+	accounts := make([]ProviderAccount, len(m.clients))
+	for i, c := range m.clients {
+		info, err := c.GetAccountInfo(ctx)
+		accounts[i] = ProviderAccount{Provider: c.Name(), Info: info, Err: err}
+	}
+	return accounts
+}
+
+// ClearCache clears every source's catalogue and EPG caches so they are refetched
+func (m *MultiClient) ClearCache() {
+	for _, src := range m.sources {
+		src.ClearCache()
+	}
+}
+
+// ClearCategoryCache clears every provider's category caches
+func (m *MultiClient) ClearCategoryCache() {
+	for _, c := range m.clients {
+		c.CategoryCache.Clear()
+		c.VodCategoryCache.Clear()
+		c.SeriesCategoryCache.Clear()
+	}
+}
+
+// counterparts finds the same live channel on the other healthy sources, in
+// priority order, matching on EPG channel ID or name
+func (m *MultiClient) counterparts(ctx context.Context, src ChannelSource, streamID int) []counterpart {
+	name, epgChannelID, ok := src.ChannelIdentity(streamID)
+	if !ok {
+		return nil
+	}
+
+	var alts []counterpart
+	for _, other := range m.sources {
+		if other == src || !other.Healthy() {
+			continue
+		}
+		items, err := other.GetLiveStreams(ctx)
+		if err != nil {
+			continue
+		}
+		for _, item := range items {
+			if (epgChannelID != "" && strings.EqualFold(item.EpgChannelID, epgChannelID)) ||
+				strings.EqualFold(strings.TrimSpace(item.Name), strings.TrimSpace(name)) {
+				alts = append(alts, counterpart{source: other, streamID: item.StreamID})
+				break
+			}
+		}
+	}
+	return alts
+}
+
+// merge queries every source concurrently and concatenates the results in
+// priority order. Failing sources are skipped; an error is only returned when
+// all of them fail. With several sources, namespace is applied to each value
+// so that IDs from different sources cannot collide.
+func merge[S ChannelSource, T any](ctx context.Context, sources []S, kind string, get func(S, context.Context) ([]T, error), namespace func(string, T) T) ([]T, error) {
+	switch len(sources) {
+	case 0:
+		return nil, nil
+	case 1:
+		return get(sources[0], ctx)
+	}
+
+	results := make([][]T, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		go func(i int, src S) {
+			defer wg.Done()
+			results[i], errs[i] = get(src, ctx)
+		}(i, src)
+	}
+	wg.Wait()
+
+	var merged []T
+	failed := 0
+	for i, src := range sources {
+		if errs[i] != nil {
+			failed++
+			slog.Warn("Source unavailable, serving the remaining sources", "source", src.Name(), "kind", kind, "error", errs[i])
+			continue
+		}
+		for _, v := range results[i] {
+			merged = append(merged, namespace(src.Name(), v))
+		}
+	}
+	if failed == len(sources) {
+		return nil, fmt.Errorf("all sources failed to return %s: %w", kind, errs[0])
+	}
+
+	return merged, nil
+}
+
+// namespaceItem prefixes an item's category ID with its source
+func namespaceItem(source string, item MediaItem) MediaItem {
+	item.CategoryID = source + ":" + item.CategoryID
+	return item
+}
+
+// namespaceCategory prefixes a category's ID with its source and labels its name
+func namespaceCategory(source string, category Category) Category {
+	category.CategoryID = source + ":" + category.CategoryID
+	category.CategoryName = fmt.Sprintf("%s (%s)", category.CategoryName, source)
+	return category
+}
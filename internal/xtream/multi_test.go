@@ -0,0 +1,203 @@
+package xtream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// fakeSource is a ChannelSource serving fixed channels
+type fakeSource struct {
+	name       string
+	items      []MediaItem
+	categories []Category
+	epg        []EpgListing
+	err        error // Returned by every fetch
+	healthy    bool
+	formats    []string // Formats CheckFormat accepts; any when empty
+}
+
+func (f *fakeSource) Name() string { return f.name }
+
+func (f *fakeSource) GetLiveStreams(ctx context.Context) ([]MediaItem, error) {
+	return f.items, f.err
+}
+
+func (f *fakeSource) GetCategories(ctx context.Context) ([]Category, error) {
+	return f.categories, f.err
+}
+
+func (f *fakeSource) GetEpgForStream(ctx context.Context, streamID int) ([]EpgListing, string, error) {
+	return f.epg, "", f.err
+}
+
+func (f *fakeSource) CheckFormat(ctx context.Context, format string) error {
+	if len(f.formats) > 0 && !slices.Contains(f.formats, format) {
+		return fmt.Errorf("format %q not allowed", format)
+	}
+	return nil
+}
+
+func (f *fakeSource) GetStreamURLWithFormat(streamID int, format string) (string, bool) {
+	for _, item := range f.items {
+		if item.StreamID == streamID {
+			return fmt.Sprintf("http://%s.example/%d.%s", f.name, streamID, format), true
+		}
+	}
+	return "", false
+}
+
+func (f *fakeSource) ChannelIdentity(streamID int) (string, string, bool) {
+	for _, item := range f.items {
+		if item.StreamID == streamID {
+			return item.Name, item.EpgChannelID, true
+		}
+	}
+	return "", "", false
+}
+
+func (f *fakeSource) Healthy() bool { return f.healthy }
+
+func (f *fakeSource) ClearCache() {}
+
+func newTestMultiClient(sources ...ChannelSource) *MultiClient {
+	m := &MultiClient{sources: sources, byName: make(map[string]ChannelSource)}
+	for _, src := range sources {
+		m.byName[src.Name()] = src
+	}
+	return m
+}
+
+func TestMerge(t *testing.T) {
+	main := func() *fakeSource {
+		return &fakeSource{name: "main", healthy: true,
+			items:      []MediaItem{{StreamID: 1, Name: "BBC One", CategoryID: "10"}},
+			categories: []Category{{CategoryID: "10", CategoryName: "UK"}}}
+	}
+	backup := func() *fakeSource {
+		return &fakeSource{name: "backup", healthy: true,
+			items:      []MediaItem{{StreamID: 1, Name: "ITV", CategoryID: "10"}, {StreamID: 2, Name: "BBC One", CategoryID: "20"}},
+			categories: []Category{{CategoryID: "10", CategoryName: "UK"}}}
+	}
+	down := func(src *fakeSource) *fakeSource {
+		src.err, src.healthy = errors.New("provider down"), false
+		return src
+	}
+
+	tests := []struct {
+		name       string
+		sources    []ChannelSource
+		items      []string // Name and namespaced category ID of each merged item
+		categories []string
+		wantErr    bool
+	}{
+		{name: "single source passed through", sources: []ChannelSource{main()},
+			items: []string{"BBC One 10"}, categories: []string{"10 UK"}},
+		{name: "category IDs namespaced by source", sources: []ChannelSource{main(), backup()},
+			items:      []string{"BBC One main:10", "ITV backup:10", "BBC One backup:20"},
+			categories: []string{"main:10 UK (main)", "backup:10 UK (backup)"}},
+		{name: "failing source skipped", sources: []ChannelSource{down(main()), backup()},
+			items:      []string{"ITV backup:10", "BBC One backup:20"},
+			categories: []string{"backup:10 UK (backup)"}},
+		{name: "all sources failing", sources: []ChannelSource{down(main()), down(backup())}, wantErr: true},
+		{name: "no sources"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMultiClient(tt.sources...)
+
+			items, err := m.GetLiveStreams(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetLiveStreams error = %v, want error %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, item := range items {
+				got = append(got, item.Name+" "+item.CategoryID)
+			}
+			if !slices.Equal(got, tt.items) {
+				t.Errorf("items = %q, want %q", got, tt.items)
+			}
+
+			categories, err := m.GetCategories(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetCategories error = %v, want error %v", err, tt.wantErr)
+			}
+			got = nil
+			for _, category := range categories {
+				got = append(got, category.CategoryID+" "+category.CategoryName)
+			}
+			if !slices.Equal(got, tt.categories) {
+				t.Errorf("categories = %q, want %q", got, tt.categories)
+			}
+		})
+	}
+}
+
+func TestLiveStreamURLFailover(t *testing.T) {
+	tests := []struct {
+		name        string
+		mainHealthy bool
+		backup      *fakeSource
+		format      string
+		wantURL     string
+		wantSource  string
+	}{
+		{name: "healthy source used", mainHealthy: true,
+			backup:  &fakeSource{name: "backup", healthy: true, items: []MediaItem{{StreamID: 7, Name: "Other", EpgChannelID: "bbc1.uk"}}},
+			wantURL: "http://main.example/1.ts", wantSource: "main"},
+		{name: "fails over by EPG channel ID",
+			backup:  &fakeSource{name: "backup", healthy: true, items: []MediaItem{{StreamID: 7, Name: "BBC 1 HD", EpgChannelID: "BBC1.uk"}}},
+			wantURL: "http://backup.example/7.ts", wantSource: "backup"},
+		{name: "fails over by name",
+			backup:  &fakeSource{name: "backup", healthy: true, items: []MediaItem{{StreamID: 8, Name: " bbc one "}}},
+			wantURL: "http://backup.example/8.ts", wantSource: "backup"},
+		{name: "unhealthy counterpart skipped",
+			backup:  &fakeSource{name: "backup", items: []MediaItem{{StreamID: 7, EpgChannelID: "bbc1.uk"}}},
+			wantURL: "http://main.example/1.ts", wantSource: "main"},
+		{name: "counterpart without the format skipped", format: "m3u8",
+			backup:  &fakeSource{name: "backup", healthy: true, formats: []string{"ts"}, items: []MediaItem{{StreamID: 7, EpgChannelID: "bbc1.uk"}}},
+			wantURL: "http://main.example/1.m3u8", wantSource: "main"},
+		{name: "no counterpart",
+			backup:  &fakeSource{name: "backup", healthy: true, items: []MediaItem{{StreamID: 9, Name: "ITV"}}},
+			wantURL: "http://main.example/1.ts", wantSource: "main"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			main := &fakeSource{name: "main", healthy: tt.mainHealthy, items: []MediaItem{{StreamID: 1, Name: "BBC One", EpgChannelID: "bbc1.uk"}}}
+			m := newTestMultiClient(main, tt.backup)
+			format := tt.format
+			if format == "" {
+				format = "ts"
+			}
+
+			url, source, ok := m.LiveStreamURL(context.Background(), "main", 1, format)
+			if !ok || url != tt.wantURL || source != tt.wantSource {
+				t.Errorf("LiveStreamURL = %q, %q, %v; want %q from %q", url, source, ok, tt.wantURL, tt.wantSource)
+			}
+		})
+	}
+
+	m := newTestMultiClient(&fakeSource{name: "main"})
+	if _, _, ok := m.LiveStreamURL(context.Background(), "unknown", 1, "ts"); ok {
+		t.Error("LiveStreamURL found an unknown source")
+	}
+}
+
+func TestGetEpgForStreamFailover(t *testing.T) {
+	main := &fakeSource{name: "main", err: errors.New("provider down"), items: []MediaItem{{StreamID: 1, Name: "BBC One", EpgChannelID: "bbc1.uk"}}}
+	backup := &fakeSource{name: "backup", healthy: true, items: []MediaItem{{StreamID: 7, EpgChannelID: "bbc1.uk"}},
+		epg: []EpgListing{{Title: "News"}}}
+	m := newTestMultiClient(main, backup)
+
+	epg, _, err := m.GetEpgForStream(context.Background(), "main", 1)
+	if err != nil || len(epg) != 1 || epg[0].Title != "News" {
+		t.Errorf("GetEpgForStream = %+v, %v; want the backup's guide", epg, err)
+	}
+
+	backup.healthy = false
+	if _, _, err := m.GetEpgForStream(context.Background(), "main", 1); err == nil || err.Error() != "provider down" {
+		t.Errorf("GetEpgForStream without a healthy counterpart = %v, want the source's error", err)
+	}
+}
@@ -0,0 +1,75 @@
+package xtream
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/git-saj/go-media-control/internal/config"
+)
+
+// defaultPrefetchCategoryRegex keeps the historical behaviour of prefetching
+// UK categories when no selection has been configured
+const defaultPrefetchCategoryRegex = "(?i)uk"
+
+// PrefetchPolicy decides which live streams get their EPG prefetched
+type PrefetchPolicy struct {
+	All                 bool
+	StreamIDs           map[int]bool
+	IncludeCategoryIDs  map[string]bool
+	ExcludeCategoryIDs  map[string]bool
+	IncludeCategoryName *regexp.Regexp
+	ExcludeCategoryName *regexp.Regexp
+}
+
+// NewPrefetchPolicy builds a PrefetchPolicy from the configuration.
+// Regexes are validated by config.LoadConfig, so compiling them here cannot fail.
+func NewPrefetchPolicy(cfg *config.Config) *PrefetchPolicy {
+	p := &PrefetchPolicy{
+		All:                cfg.EpgPrefetchAll,
+		StreamIDs:          make(map[int]bool),
+		IncludeCategoryIDs: make(map[string]bool),
+		ExcludeCategoryIDs: make(map[string]bool),
+	}
+	for _, id := range cfg.EpgPrefetchStreamIDs {
+		if n, err := strconv.Atoi(id); err == nil {
+			p.StreamIDs[n] = true
+		}
+	}
+	for _, id := range cfg.EpgPrefetchCategoryIDs {
+		p.IncludeCategoryIDs[id] = true
+	}
+	for _, id := range cfg.EpgPrefetchExcludeCategoryIDs {
+		p.ExcludeCategoryIDs[id] = true
+	}
+
+	includeRegex := cfg.EpgPrefetchCategoryRegex
+	if includeRegex == "" && !p.All && len(p.StreamIDs) == 0 && len(p.IncludeCategoryIDs) == 0 {
+		includeRegex = defaultPrefetchCategoryRegex
+	}
+	if includeRegex != "" {
+		p.IncludeCategoryName = regexp.MustCompile(includeRegex)
+	}
+	if cfg.EpgPrefetchExcludeCategoryRegex != "" {
+		p.ExcludeCategoryName = regexp.MustCompile(cfg.EpgPrefetchExcludeCategoryRegex)
+	}
+
+	return p
+}
+
+// Selects reports whether a stream should be prefetched. Explicit stream IDs
+// always win, exclusions beat inclusions, and "all" includes whatever is left.
+func (p *PrefetchPolicy) Selects(item MediaItem, categoryName string) bool {
+	if p.StreamIDs[item.StreamID] {
+		return true
+	}
+	if p.ExcludeCategoryIDs[item.CategoryID] {
+		return false
+	}
+	if p.ExcludeCategoryName != nil && p.ExcludeCategoryName.MatchString(categoryName) {
+		return false
+	}
+	if p.All || p.IncludeCategoryIDs[item.CategoryID] {
+		return true
+	}
+	return p.IncludeCategoryName != nil && categoryName != "" && p.IncludeCategoryName.MatchString(categoryName)
+}
@@ -0,0 +1,106 @@
+package xtream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// cancelOnClose releases a request's timeout once its body has been read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// get issues a GET against player_api with the client's per-attempt timeout
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	return c.getWithTimeout(ctx, url, c.timeout)
+}
+
+// getWithTimeout issues a GET against the provider, retrying network errors and
+// 5xx responses with exponential backoff and jitter. Each attempt, including
+// reading the body, is limited to timeout, and the whole call ends when ctx is
+// cancelled. The outcome is recorded so failing providers can be skipped by the
+// MultiClient; a final 5xx response is returned for the caller to report.
+func (c *Client) getWithTimeout(ctx context.Context, url string, timeout time.Duration) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, url, nil)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			c.recordResult(true)
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		// The caller went away; that says nothing about the provider
+		if ctx.Err() != nil {
+			if err == nil {
+				resp.Body.Close()
+			}
+			cancel()
+			return nil, ctx.Err()
+		}
+
+		if attempt >= c.retries {
+			c.recordResult(false)
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = fmt.Sprintf("status %d", resp.StatusCode)
+			resp.Body.Close()
+		}
+		cancel()
+
+		delay := c.backoff(attempt)
+		slog.Warn("Retrying Xtream request", "provider", c.name, "attempt", attempt+1, "delay", delay, "reason", reason)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before retry attempt+1: the base backoff doubled per
+// attempt, of which the upper half is randomised so clients do not retry in step
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retryBackoff << attempt
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// recordResult notes whether the provider answered a request
+func (c *Client) recordResult(ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ok {
+		c.lastSuccess = time.Now()
+	} else {
+		c.lastFailure = time.Now()
+	}
+}
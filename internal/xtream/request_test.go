@@ -0,0 +1,120 @@
+package xtream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// statusServer answers each request with the next of statuses, repeating the
+// last one, and counts the requests
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(attempts.Add(1))
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &attempts
+}
+
+func newRequestClient(retries int, backoff time.Duration) *Client {
+	return &Client{
+		name:         "test",
+		timeout:      5 * time.Second,
+		retries:      retries,
+		retryBackoff: backoff,
+		httpClient:   &http.Client{},
+	}
+}
+
+func TestGetRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		status   int // Status of the response returned
+		attempts int32
+		healthy  bool // Whether the provider is recorded as answering
+	}{
+		{name: "success", statuses: []int{http.StatusOK}, retries: 2, status: http.StatusOK, attempts: 1, healthy: true},
+		{name: "5xx retried then success", statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK}, retries: 2,
+			status: http.StatusOK, attempts: 3, healthy: true},
+		{name: "5xx exhausts retries", statuses: []int{http.StatusInternalServerError}, retries: 2, status: http.StatusInternalServerError, attempts: 3},
+		{name: "4xx not retried", statuses: []int{http.StatusForbidden, http.StatusOK}, retries: 2, status: http.StatusForbidden, attempts: 1, healthy: true},
+		{name: "no retries configured", statuses: []int{http.StatusBadGateway, http.StatusOK}, status: http.StatusBadGateway, attempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, attempts := statusServer(t, tt.statuses...)
+			c := newRequestClient(tt.retries, time.Millisecond)
+
+			resp, err := c.get(context.Background(), srv.URL)
+			if err != nil {
+				t.Fatalf("get failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if n := attempts.Load(); n != tt.attempts {
+				t.Errorf("server saw %d attempts, want %d", n, tt.attempts)
+			}
+			if healthy := c.lastFailure.IsZero(); healthy != tt.healthy {
+				t.Errorf("recorded failure = %v, want %v", c.lastFailure, !tt.healthy)
+			}
+		})
+	}
+}
+
+func TestGetRetriesNetworkErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	c := newRequestClient(1, time.Millisecond)
+
+	if _, err := c.get(context.Background(), srv.URL); err == nil {
+		t.Fatal("expected an error from a closed server")
+	}
+	if c.lastFailure.IsZero() {
+		t.Error("failure was not recorded")
+	}
+}
+
+func TestGetCancelStopsBackoff(t *testing.T) {
+	srv, attempts := statusServer(t, http.StatusBadGateway)
+	c := newRequestClient(3, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.get(ctx, srv.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("get error = %v, want the context's", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("get returned after %v, want it to stop sleeping when cancelled", elapsed)
+	}
+	if n := attempts.Load(); n != 1 {
+		t.Errorf("server saw %d attempts, want 1", n)
+	}
+	// The caller giving up says nothing about the provider
+	if !c.lastFailure.IsZero() {
+		t.Error("cancellation was recorded as a provider failure")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	c := newRequestClient(3, 100*time.Millisecond)
+	for attempt, base := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		for range 20 {
+			if delay := c.backoff(attempt); delay < base/2 || delay > base {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", attempt, delay, base/2, base)
+			}
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package xtream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamTypeSeries marks a MediaItem as a series rather than a playable stream
+const StreamTypeSeries = "series"
+
+// SeriesInfo holds the details and episodes returned by get_series_info
+type SeriesInfo struct {
+	Provider    string
+	SeriesID    int
+	Name        string
+	Plot        string
+	Genre       string
+	Cover       string
+	ReleaseDate string
+	Rating      string
+	Seasons     []Season
+}
+
+// Season groups the episodes of a single season
+type Season struct {
+	Number   int
+	Name     string
+	Episodes []Episode
+}
+
+// Episode represents a single playable episode of a series
+type Episode struct {
+	ID                 int
+	EpisodeNum         int
+	Season             int
+	Title              string
+	ContainerExtension string
+	Plot               string
+	Duration           string
+	AirDate            string
+	Image              string
+	StreamURL          string
+}
+
+// episodeCacheSize bounds how many episodes of opened series are remembered
+// for sending; the least recently used are dropped first
+const episodeCacheSize = 20000
+
+// episodeTTL is how long an opened series' episodes can be sent without
+// opening it again
+const episodeTTL = time.Hour * 24
+
+// episodeEntry is what sending an episode needs: its stream URL, and a name
+// and image for messages about it
+type episodeEntry struct {
+	url   string
+	name  string
+	image string
+}
+
+// fetchSeries fetches the series list from the Xtream Code API
+func (c *Client) fetchSeries(ctx context.Context) ([]MediaItem, error) {
+	url := fmt.Sprintf("%s/player_api.php?username=%s&password=%s&action=get_series",
+		c.BaseURL, c.Username, c.Password)
+
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch series: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code for series: %d", resp.StatusCode)
+	}
+
+	var rawSeries []struct {
+		Name       string      `json:"name"`
+		SeriesID   json.Number `json:"series_id"`
+		Cover      string      `json:"cover"`
+		CategoryID json.Number `json:"category_id"`
+		Rating     any         `json:"rating"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rawSeries); err != nil {
+		return nil, fmt.Errorf("failed to decode series: %w", err)
+	}
+
+	media := make([]MediaItem, len(rawSeries))
+	for i, item := range rawSeries {
+		seriesIDInt, _ := strconv.Atoi(string(item.SeriesID))
+		media[i] = MediaItem{
+			Provider:   c.name,
+			Name:       item.Name,
+			StreamID:   seriesIDInt,
+			Logo:       item.Cover,
+			CategoryID: string(item.CategoryID),
+			StreamType: StreamTypeSeries,
+			Rating:     ratingString(item.Rating),
+		}
+	}
+
+	return media, nil
+}
+
+// GetSeries retrieves the series list, using the cache if available
+func (c *Client) GetSeries(ctx context.Context) ([]MediaItem, error) {
+	return c.SeriesCache.Load(ctx, time.Minute*10, c.fetchSeries)
+}
+
+// FetchSeriesCategories fetches series categories from the Xtream Code API
+func (c *Client) FetchSeriesCategories(ctx context.Context) ([]Category, error) {
+	url := fmt.Sprintf("%s/player_api.php?username=%s&password=%s&action=get_series_categories",
+		c.BaseURL, c.Username, c.Password)
+
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch series categories: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code for series categories: %d", resp.StatusCode)
+	}
+
+	var categories []Category
+	if err := json.NewDecoder(resp.Body).Decode(&categories); err != nil {
+		return nil, fmt.Errorf("failed to decode series categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// GetSeriesCategories fetches series categories, using the cache if available
+func (c *Client) GetSeriesCategories(ctx context.Context) ([]Category, error) {
+	return c.SeriesCategoryCache.Load(ctx, time.Hour*24, c.FetchSeriesCategories)
+}
+
+// FetchSeriesInfo fetches the seasons and episodes of a series and registers
+// the episode stream URLs so they can be sent
+func (c *Client) FetchSeriesInfo(ctx context.Context, seriesID int) (*SeriesInfo, error) {
+	url := fmt.Sprintf("%s/player_api.php?username=%s&password=%s&action=get_series_info&series_id=%d",
+		c.BaseURL, c.Username, c.Password, seriesID)
+
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch series info for %d: %w", seriesID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code for series info %d: %d", seriesID, resp.StatusCode)
+	}
+
+	var raw struct {
+		Seasons []struct {
+			SeasonNumber any    `json:"season_number"`
+			Name         string `json:"name"`
+		} `json:"seasons"`
+		Info struct {
+			Name        string `json:"name"`
+			Plot        string `json:"plot"`
+			Genre       string `json:"genre"`
+			Cover       string `json:"cover"`
+			ReleaseDate string `json:"releaseDate"`
+			Rating      any    `json:"rating"`
+		} `json:"info"`
+		Episodes map[string][]struct {
+			ID                 any    `json:"id"`
+			EpisodeNum         any    `json:"episode_num"`
+			Title              string `json:"title"`
+			ContainerExtension string `json:"container_extension"`
+			Info               struct {
+				Plot        string `json:"plot"`
+				Duration    string `json:"duration"`
+				AirDate     string `json:"air_date"`
+				ReleaseDate string `json:"releasedate"`
+				Image       string `json:"movie_image"`
+			} `json:"info"`
+		} `json:"episodes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode series info for %d: %w", seriesID, err)
+	}
+
+	seasonNames := make(map[int]string, len(raw.Seasons))
+	for _, s := range raw.Seasons {
+		seasonNames[intValue(s.SeasonNumber)] = s.Name
+	}
+
+	info := &SeriesInfo{
+		Provider:    c.name,
+		SeriesID:    seriesID,
+		Name:        raw.Info.Name,
+		Plot:        raw.Info.Plot,
+		Genre:       raw.Info.Genre,
+		Cover:       raw.Info.Cover,
+		ReleaseDate: raw.Info.ReleaseDate,
+		Rating:      ratingString(raw.Info.Rating),
+	}
+
+	for key, rawEpisodes := range raw.Episodes {
+		number, _ := strconv.Atoi(key)
+		season := Season{Number: number, Name: seasonNames[number]}
+		if season.Name == "" {
+			season.Name = fmt.Sprintf("Season %d", number)
+		}
+		for _, e := range rawEpisodes {
+			ext := e.ContainerExtension
+			if ext == "" {
+				ext = "mp4"
+			}
+			airDate := e.Info.AirDate
+			if airDate == "" {
+				airDate = e.Info.ReleaseDate
+			}
+			ep := Episode{
+				ID:                 intValue(e.ID),
+				EpisodeNum:         intValue(e.EpisodeNum),
+				Season:             number,
+				Title:              e.Title,
+				ContainerExtension: ext,
+				Plot:               e.Info.Plot,
+				Duration:           e.Info.Duration,
+				AirDate:            airDate,
+				Image:              e.Info.Image,
+			}
+			ep.StreamURL = fmt.Sprintf("%s/series/%s/%s/%d.%s",
+				c.BaseURL, c.Username, c.Password, ep.ID, ext)
+			// Providers often put the series name and numbering in the title already
+			entry := episodeEntry{url: ep.StreamURL, name: ep.Title, image: info.Cover}
+			if !strings.Contains(ep.Title, info.Name) {
+				entry.name = fmt.Sprintf("%s S%02dE%02d %s", info.Name, number, ep.EpisodeNum, ep.Title)
+			}
+			c.episodes.Set(ep.ID, entry, episodeTTL)
+			season.Episodes = append(season.Episodes, ep)
+		}
+		sort.Slice(season.Episodes, func(i, j int) bool {
+			return season.Episodes[i].EpisodeNum < season.Episodes[j].EpisodeNum
+		})
+		info.Seasons = append(info.Seasons, season)
+	}
+	sort.Slice(info.Seasons, func(i, j int) bool {
+		return info.Seasons[i].Number < info.Seasons[j].Number
+	})
+
+	return info, nil
+}
+
+// GetEpisodeURL retrieves the stream URL for an episode of a series opened recently
+func (c *Client) GetEpisodeURL(episodeID int) (string, bool) {
+	entry, ok := c.episodes.Get(episodeID)
+	return entry.url, ok
+}
+
+// EpisodeLabel returns a display name and cover image for an episode of a
+// series opened recently
+func (c *Client) EpisodeLabel(episodeID int) (string, string, bool) {
+	entry, ok := c.episodes.Get(episodeID)
+	return entry.name, entry.image, ok
+}
+
+// intValue normalises IDs and numbers, which providers send as either strings or numbers
+func intValue(v any) int {
+	switch n := v.(type) {
+	case string:
+		i, _ := strconv.Atoi(n)
+		return i
+	case float64:
+		return int(n)
+	default:
+		return 0
+	}
+}
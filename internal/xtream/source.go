@@ -0,0 +1,25 @@
+package xtream
+
+import "context"
+
+// ChannelSource is a provider of live channels, such as an Xtream Code panel or
+// a plain M3U playlist. Stream and category IDs only need to be unique within a
+// source; the MultiClient namespaces them by source name.
+type ChannelSource interface {
+	// Name identifies the source and namespaces its channels
+	Name() string
+	GetLiveStreams(ctx context.Context) ([]MediaItem, error)
+	GetCategories(ctx context.Context) ([]Category, error)
+	GetEpgForStream(ctx context.Context, streamID int) ([]EpgListing, string, error)
+	// CheckFormat reports whether the source can deliver the given output format
+	CheckFormat(ctx context.Context, format string) error
+	GetStreamURLWithFormat(streamID int, format string) (string, bool)
+	// ChannelIdentity returns the last known name and EPG channel ID of a stream,
+	// used to find the same channel on another source
+	ChannelIdentity(streamID int) (name, epgChannelID string, ok bool)
+	// Healthy reports whether the source answered its most recent request
+	Healthy() bool
+	ClearCache()
+}
+
+var _ ChannelSource = (*Client)(nil)
@@ -0,0 +1,45 @@
+package xtream
+
+import (
+	"fmt"
+	"time"
+)
+
+// StreamTypeTimeshift marks a send request as catch-up playback of a past programme
+const StreamTypeTimeshift = "timeshift"
+
+// timeshiftLayout is the start time format Xtream panels expect in timeshift URLs
+const timeshiftLayout = "2006-01-02:15-04"
+
+// CanReplay reports whether a programme has finished and is still inside the
+// channel's catch-up window
+func (c *Client) CanReplay(streamID int, program EpgListing) bool {
+	c.mu.RLock()
+	days := c.archiveDays[streamID]
+	c.mu.RUnlock()
+	return canReplay(days, program.Start, program.End, time.Now())
+}
+
+func canReplay(archiveDays int, start, end int64, now time.Time) bool {
+	if archiveDays <= 0 || end <= start || end > now.Unix() {
+		return false
+	}
+	return start >= now.Add(-time.Duration(archiveDays)*24*time.Hour).Unix()
+}
+
+// GetTimeshiftURL builds the catch-up URL for a past programme on an archived channel
+func (c *Client) GetTimeshiftURL(streamID int, start, end int64) (string, bool) {
+	c.mu.RLock()
+	days := c.archiveDays[streamID]
+	location := c.timeshiftLocation
+	c.mu.RUnlock()
+	if !canReplay(days, start, end, time.Now()) {
+		return "", false
+	}
+
+	// Duration is in whole minutes, rounded up so the end of the programme isn't cut off
+	minutes := (end - start + 59) / 60
+	startStr := time.Unix(start, 0).In(location).Format(timeshiftLayout)
+	return fmt.Sprintf("%s/timeshift/%s/%s/%d/%s/%d.ts",
+		c.BaseURL, c.Username, c.Password, minutes, startStr, streamID), true
+}
@@ -0,0 +1,211 @@
+package xtream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Stream types reported by the Xtream Code API
+const (
+	StreamTypeLive  = "live"
+	StreamTypeMovie = "movie"
+)
+
+// VodInfo holds the details returned by get_vod_info for a single movie
+type VodInfo struct {
+	Provider           string
+	StreamID           int
+	Name               string
+	ContainerExtension string
+	Plot               string
+	Genre              string
+	Director           string
+	Cast               string
+	ReleaseDate        string
+	Duration           string
+	Rating             string
+	Cover              string
+}
+
+// fetchVodStreams fetches movies from the Xtream Code API and constructs StreamURL
+func (c *Client) fetchVodStreams(ctx context.Context) ([]MediaItem, error) {
+	url := fmt.Sprintf("%s/player_api.php?username=%s&password=%s&action=get_vod_streams",
+		c.BaseURL, c.Username, c.Password)
+
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch vod streams: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code for vod streams: %d", resp.StatusCode)
+	}
+
+	var rawMedia []struct {
+		Name               string      `json:"name"`
+		StreamID           json.Number `json:"stream_id"`
+		Logo               string      `json:"stream_icon"`
+		CategoryID         json.Number `json:"category_id"`
+		Rating             any         `json:"rating"`
+		ContainerExtension string      `json:"container_extension"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rawMedia); err != nil {
+		return nil, fmt.Errorf("failed to decode vod streams: %w", err)
+	}
+
+	media := make([]MediaItem, len(rawMedia))
+	for i, item := range rawMedia {
+		streamIDInt, _ := strconv.Atoi(string(item.StreamID))
+		ext := item.ContainerExtension
+		if ext == "" {
+			ext = "mp4"
+		}
+		media[i] = MediaItem{
+			Provider:           c.name,
+			Name:               item.Name,
+			StreamID:           streamIDInt,
+			Logo:               item.Logo,
+			CategoryID:         string(item.CategoryID),
+			StreamType:         StreamTypeMovie,
+			ContainerExtension: ext,
+			Rating:             ratingString(item.Rating),
+			StreamURL: fmt.Sprintf("%s/movie/%s/%s/%d.%s",
+				c.BaseURL, c.Username, c.Password, streamIDInt, ext),
+		}
+	}
+
+	return media, nil
+}
+
+// GetVodStreams retrieves movies, using the cache if available
+func (c *Client) GetVodStreams(ctx context.Context) ([]MediaItem, error) {
+	return c.VodCache.Load(ctx, time.Minute*10, c.loadVodStreams)
+}
+
+// loadVodStreams fetches the movies and indexes their URLs for sending
+func (c *Client) loadVodStreams(ctx context.Context) ([]MediaItem, error) {
+	items, err := c.fetchVodStreams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.indexVodStreams(items)
+
+	return items, nil
+}
+
+// indexVodStreams records the movies' URLs for sending
+func (c *Client) indexVodStreams(items []MediaItem) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.vodURLs = make(map[int]string, len(items))
+	for _, m := range items {
+		c.vodURLs[m.StreamID] = m.StreamURL
+	}
+}
+
+// FetchVodCategories fetches movie categories from the Xtream Code API
+func (c *Client) FetchVodCategories(ctx context.Context) ([]Category, error) {
+	url := fmt.Sprintf("%s/player_api.php?username=%s&password=%s&action=get_vod_categories",
+		c.BaseURL, c.Username, c.Password)
+
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch vod categories: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code for vod categories: %d", resp.StatusCode)
+	}
+
+	var categories []Category
+	if err := json.NewDecoder(resp.Body).Decode(&categories); err != nil {
+		return nil, fmt.Errorf("failed to decode vod categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// GetVodCategories fetches movie categories, using the cache if available
+func (c *Client) GetVodCategories(ctx context.Context) ([]Category, error) {
+	return c.VodCategoryCache.Load(ctx, time.Hour*24, c.FetchVodCategories)
+}
+
+// FetchVodInfo fetches the details of a single movie
+func (c *Client) FetchVodInfo(ctx context.Context, vodID int) (*VodInfo, error) {
+	url := fmt.Sprintf("%s/player_api.php?username=%s&password=%s&action=get_vod_info&vod_id=%d",
+		c.BaseURL, c.Username, c.Password, vodID)
+
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch vod info for %d: %w", vodID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code for vod info %d: %d", vodID, resp.StatusCode)
+	}
+
+	var raw struct {
+		Info struct {
+			Plot        string `json:"plot"`
+			Genre       string `json:"genre"`
+			Director    string `json:"director"`
+			Cast        string `json:"cast"`
+			ReleaseDate string `json:"releasedate"`
+			Duration    string `json:"duration"`
+			Rating      any    `json:"rating"`
+			Cover       string `json:"movie_image"`
+		} `json:"info"`
+		MovieData struct {
+			StreamID           json.Number `json:"stream_id"`
+			Name               string      `json:"name"`
+			ContainerExtension string      `json:"container_extension"`
+		} `json:"movie_data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode vod info for %d: %w", vodID, err)
+	}
+
+	streamID, _ := strconv.Atoi(string(raw.MovieData.StreamID))
+	return &VodInfo{
+		Provider:           c.name,
+		StreamID:           streamID,
+		Name:               raw.MovieData.Name,
+		ContainerExtension: raw.MovieData.ContainerExtension,
+		Plot:               raw.Info.Plot,
+		Genre:              raw.Info.Genre,
+		Director:           raw.Info.Director,
+		Cast:               raw.Info.Cast,
+		ReleaseDate:        raw.Info.ReleaseDate,
+		Duration:           raw.Info.Duration,
+		Rating:             ratingString(raw.Info.Rating),
+		Cover:              raw.Info.Cover,
+	}, nil
+}
+
+// GetMovieURL retrieves the stream URL for a given movie ID
+func (c *Client) GetMovieURL(streamID int) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	url, ok := c.vodURLs[streamID]
+	return url, ok
+}
+
+// ratingString normalises ratings, which providers send as either strings or numbers
+func ratingString(v any) string {
+	switch r := v.(type) {
+	case string:
+		return r
+	case float64:
+		return strconv.FormatFloat(r, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,202 @@
+package xtream
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// xmltvProgramme mirrors a <programme> element of an XMLTV document
+type xmltvProgramme struct {
+	Start   string      `xml:"start,attr"`
+	Stop    string      `xml:"stop,attr"`
+	Channel string      `xml:"channel,attr"`
+	Titles  []xmltvText `xml:"title"`
+	Descs   []xmltvText `xml:"desc"`
+}
+
+// xmltvText is a possibly language-tagged text node such as <title lang="en">
+type xmltvText struct {
+	Lang  string `xml:"lang,attr"`
+	Value string `xml:",chardata"`
+}
+
+// XMLTV timestamps, with and without a UTC offset
+var xmltvLayouts = []string{"20060102150405 -0700", "20060102150405"}
+
+// ParseXMLTV streams an XMLTV document and groups its programmes by channel ID.
+// Only one <programme> element is held in memory at a time while decoding.
+func ParseXMLTV(r io.Reader) (map[string][]EpgListing, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = xmltvCharsetReader
+
+	index := make(map[string][]EpgListing)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read XMLTV: %w", err)
+		}
+
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "programme" {
+			continue
+		}
+
+		var p xmltvProgramme
+		if err := dec.DecodeElement(&p, &se); err != nil {
+			return nil, fmt.Errorf("failed to decode XMLTV programme: %w", err)
+		}
+		start, err := parseXMLTVTime(p.Start)
+		if err != nil || p.Channel == "" {
+			continue
+		}
+		stop, err := parseXMLTVTime(p.Stop)
+		if err != nil {
+			continue
+		}
+
+		listing := EpgListing{
+			ChannelId: p.Channel,
+			Start:     start.Unix(),
+			End:       stop.Unix(),
+		}
+		if len(p.Titles) > 0 {
+			listing.Title = strings.TrimSpace(p.Titles[0].Value)
+			listing.Lang = p.Titles[0].Lang
+		}
+		if len(p.Descs) > 0 {
+			listing.Description = strings.TrimSpace(p.Descs[0].Value)
+		}
+		index[p.Channel] = append(index[p.Channel], listing)
+	}
+
+	for _, listings := range index {
+		sort.Slice(listings, func(i, j int) bool {
+			return listings[i].Start < listings[j].Start
+		})
+	}
+
+	return index, nil
+}
+
+func parseXMLTVTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range xmltvLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid XMLTV time %q", s)
+}
+
+// xmltvCharsetReader handles the non-UTF-8 encodings providers commonly declare
+func xmltvCharsetReader(label string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(label) {
+	case "", "utf-8", "utf8", "us-ascii":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1":
+		return &latin1Reader{r: bufio.NewReader(input)}, nil
+	}
+	return nil, fmt.Errorf("unsupported XMLTV charset %q", label)
+}
+
+// latin1Reader converts an ISO-8859-1 byte stream to UTF-8
+type latin1Reader struct {
+	r       io.ByteReader
+	pending []byte
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(l.pending) > 0 {
+			c := copy(p[n:], l.pending)
+			l.pending = l.pending[c:]
+			n += c
+			continue
+		}
+		b, err := l.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if b < utf8.RuneSelf {
+			p[n] = b
+			n++
+			continue
+		}
+		l.pending = utf8.AppendRune(l.pending[:0], rune(b))
+	}
+	return n, nil
+}
+
+// FetchXMLTV downloads xmltv.php once and parses it into a per-channel index
+func (c *Client) FetchXMLTV(ctx context.Context) (map[string][]EpgListing, error) {
+	url := fmt.Sprintf("%s/xmltv.php?username=%s&password=%s",
+		c.BaseURL, c.Username, c.Password)
+
+	resp, err := c.getWithTimeout(ctx, url, c.xmltvTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch XMLTV: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code for XMLTV: %d", resp.StatusCode)
+	}
+
+	return ParseXMLTV(resp.Body)
+}
+
+// LoadXMLTV refreshes the XMLTV index used by GetEpgForStream
+func (c *Client) LoadXMLTV(ctx context.Context) error {
+	start := time.Now()
+	index, err := c.FetchXMLTV(ctx)
+	if err != nil {
+		return err
+	}
+
+	programmes := 0
+	for _, listings := range index {
+		programmes += len(listings)
+	}
+	c.XmltvCache.Set(index, time.Hour*24)
+	slog.Info("XMLTV loaded", "provider", c.name, "channels", len(index), "programmes", programmes, "duration", time.Since(start))
+
+	return nil
+}
+
+// xmltvListings returns the XMLTV programmes for a stream, matched by its epg_channel_id
+func (c *Client) xmltvListings(streamID int) ([]EpgListing, bool) {
+	c.mu.RLock()
+	epgChannelID := c.epgChannelIDs[streamID]
+	c.mu.RUnlock()
+	if epgChannelID == "" {
+		return nil, false
+	}
+
+	index, ok := c.XmltvCache.Get()
+	if !ok {
+		return nil, false
+	}
+	listings, ok := index[epgChannelID]
+	if !ok || len(listings) == 0 {
+		return nil, false
+	}
+
+	// Return a copy to avoid modifying cache
+	return append([]EpgListing(nil), listings...), true
+}
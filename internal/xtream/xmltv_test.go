@@ -0,0 +1,127 @@
+package xtream
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/git-saj/go-media-control/internal/cache"
+)
+
+const xmltvFixture = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE tv SYSTEM "xmltv.dtd">
+<tv generator-info-name="test">
+  <channel id="bbc1.uk"><display-name>BBC One</display-name></channel>
+  <programme start="20240101130000 +0100" stop="20240101140000 +0100" channel="bbc1.uk">
+    <title lang="en"> Afternoon News </title>
+    <title lang="cy">Newyddion</title>
+    <desc lang="en">Headlines &amp; weather</desc>
+  </programme>
+  <programme start="20240101110000" stop="20240101120000" channel="bbc1.uk">
+    <title lang="en">Morning Show</title>
+  </programme>
+  <programme start="not a time" stop="20240101150000 +0000" channel="bbc1.uk">
+    <title>Bad start</title>
+  </programme>
+  <programme start="20240101150000 +0000" stop="" channel="bbc1.uk">
+    <title>Bad stop</title>
+  </programme>
+  <programme start="20240101150000 +0000" stop="20240101160000 +0000">
+    <title>No channel</title>
+  </programme>
+  <programme start="20240101090000 +0000" stop="20240101100000 +0000" channel="unlisted.uk">
+    <title>Channel without a channel element</title>
+  </programme>
+</tv>`
+
+func TestParseXMLTV(t *testing.T) {
+	index, err := ParseXMLTV(strings.NewReader(xmltvFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index) != 2 {
+		t.Errorf("index has %d channels, want 2", len(index))
+	}
+
+	utc := func(hour int) int64 { return time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC).Unix() }
+	want := []EpgListing{
+		// Sorted by start; a timestamp without an offset is UTC
+		{ChannelId: "bbc1.uk", Start: utc(11), End: utc(12), Lang: "en", Title: "Morning Show"},
+		{ChannelId: "bbc1.uk", Start: utc(12), End: utc(13), Lang: "en", Title: "Afternoon News", Description: "Headlines & weather"},
+	}
+	got := index["bbc1.uk"]
+	if len(got) != len(want) {
+		t.Fatalf("bbc1.uk has %d programmes, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("programme %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if listings := index["unlisted.uk"]; len(listings) != 1 || listings[0].Title != "Channel without a channel element" {
+		t.Errorf("unlisted.uk = %+v", listings)
+	}
+}
+
+func TestParseXMLTVLatin1(t *testing.T) {
+	doc := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		"<tv><programme start=\"20240101120000 +0000\" stop=\"20240101130000 +0000\" channel=\"tf1.fr\">" +
+		"<title>Journal t\xe9l\xe9vis\xe9</title><desc>Caf\xe9 cr\xe8me</desc></programme></tv>"
+
+	index, err := ParseXMLTV(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	listings := index["tf1.fr"]
+	if len(listings) != 1 || listings[0].Title != "Journal télévisé" || listings[0].Description != "Café crème" {
+		t.Errorf("tf1.fr = %+v", listings)
+	}
+}
+
+func TestParseXMLTVErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{name: "unsupported charset", doc: `<?xml version="1.0" encoding="KOI8-R"?><tv></tv>`},
+		{name: "truncated document", doc: `<tv><programme start="20240101120000" stop="20240101130000" channel="a"><title>Cut`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseXMLTV(strings.NewReader(tt.doc)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestXMLTVListings(t *testing.T) {
+	c := &Client{
+		XmltvCache:    cache.New[map[string][]EpgListing](),
+		epgChannelIDs: map[int]string{1: "bbc1.uk", 2: "missing.uk"},
+	}
+	index, err := ParseXMLTV(strings.NewReader(xmltvFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.XmltvCache.Set(index, time.Hour)
+
+	listings, ok := c.xmltvListings(1)
+	if !ok || len(listings) != 2 {
+		t.Fatalf("xmltvListings(1) = %+v, %v", listings, ok)
+	}
+	// The caller gets a copy
+	listings[0].Title = "changed"
+	if index["bbc1.uk"][0].Title == "changed" {
+		t.Error("xmltvListings returned the cached slice")
+	}
+
+	// A channel missing from the index, or a stream without an EPG channel ID,
+	// falls back to the per-stream API
+	for _, streamID := range []int{2, 3} {
+		if listings, ok := c.xmltvListings(streamID); ok {
+			t.Errorf("xmltvListings(%d) = %+v, want no match", streamID, listings)
+		}
+	}
+}
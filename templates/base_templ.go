@@ -128,7 +128,7 @@ func Base(content templ.Component, basePath string) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "</body></html>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<!-- Outcome of sends, from the /api/send response --><div id=\"send-toast\" class=\"toast toast-end\"></div><script>\n\t\t\t\tdocument.body.addEventListener(\"htmx:afterRequest\", function (evt) {\n\t\t\t\t\tif (!evt.detail.pathInfo.requestPath.endsWith(\"api/send\")) {\n\t\t\t\t\t\t// Other actions only report a missing permission, which HTMX would not swap in\n\t\t\t\t\t\tif (evt.detail.xhr.status === 403) {\n\t\t\t\t\t\t\tshowToast(\"alert-error\", evt.detail.xhr.responseText.trim());\n\t\t\t\t\t\t}\n\t\t\t\t\t\treturn;\n\t\t\t\t\t}\n\t\t\t\t\tlet status = \"dropped\", message = evt.detail.xhr.responseText.trim();\n\t\t\t\t\ttry {\n\t\t\t\t\t\tconst body = JSON.parse(message);\n\t\t\t\t\t\tstatus = body.status;\n\t\t\t\t\t\tmessage = body.error || (status === \"queued\" ? \"Queued, waiting on a rate limit\" : \"Sent\");\n\t\t\t\t\t} catch (e) {\n\t\t\t\t\t\t// Validation errors such as an unknown channel are plain text\n\t\t\t\t\t}\n\t\t\t\t\tshowToast(status === \"delivered\" ? \"alert-success\" : status === \"queued\" ? \"alert-info\" : \"alert-error\", message);\n\t\t\t\t});\n\t\t\t\tfunction showToast(kind, message) {\n\t\t\t\t\tconst alert = document.createElement(\"div\");\n\t\t\t\t\talert.className = \"alert \" + kind;\n\t\t\t\t\talert.textContent = message;\n\t\t\t\t\tdocument.getElementById(\"send-toast\").appendChild(alert);\n\t\t\t\t\tsetTimeout(function () { alert.remove(); }, 4000);\n\t\t\t\t}\n\t\t\t</script></body></html>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}